@@ -0,0 +1,20 @@
+// Symbols used by app.rc
+#define IDI_APP        1
+#define IDC_HAND       2
+#define IDS_BASE       1000
+#define IDS_HELLO      (IDS_BASE + 1)
+#define IDS_BYE        (IDS_BASE + 2)
+#define IDR_MAIN       100
+#define IDD_ABOUT      101
+#define IDM_COPY       200
+#define IDM_ALL        201
+#define IDM_REFRESH    202
+#define IDM_SAVE       203
+#define IDM_DELETE     204
+#define IDM_EXIT       205
+#define IDM_HELP       206
+#define IDC_NAME       300
+#define IDC_LIST       301
+#define LVS_REPORT     0x0001
+
+This line is not a directive, so it is ignored.
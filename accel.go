@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	errInvalidAccelTable = "invalid accelerator table"
+	errInvalidKey        = "invalid key"
+	errUnknownModifier   = "unknown modifier"
+	errASCIIModifier     = "Ctrl and Shift modifiers require a virtual key"
+)
+
+// Accelerator flags
+// https://docs.microsoft.com/en-us/windows/win32/menurc/acceltableentry
+const (
+	accelVirtKey  = 0x01
+	accelNoInvert = 0x02
+	accelShift    = 0x04
+	accelControl  = 0x08
+	accelAlt      = 0x10
+	accelLast     = 0x80
+)
+
+const (
+	keyTypeVirtKey = "virtkey"
+	keyTypeASCII   = "ascii"
+)
+
+// accelerator is the JSON form of an accelerator table entry.
+type accelerator struct {
+	Key       string `json:"key"`
+	Type      string `json:"type,omitempty"`
+	Modifiers string `json:"modifiers,omitempty"`
+	NoInvert  bool   `json:"noinvert,omitempty"`
+	ID        uint16 `json:"id"`
+}
+
+// Virtual key codes
+// https://docs.microsoft.com/en-us/windows/win32/inputdev/virtual-key-codes
+var virtKeys = map[string]uint16{
+	"VK_BACK":                0x08,
+	"VK_TAB":                 0x09,
+	"VK_CLEAR":               0x0C,
+	"VK_RETURN":              0x0D,
+	"VK_SHIFT":               0x10,
+	"VK_CONTROL":             0x11,
+	"VK_MENU":                0x12,
+	"VK_PAUSE":               0x13,
+	"VK_CAPITAL":             0x14,
+	"VK_ESCAPE":              0x1B,
+	"VK_SPACE":               0x20,
+	"VK_PRIOR":               0x21,
+	"VK_NEXT":                0x22,
+	"VK_END":                 0x23,
+	"VK_HOME":                0x24,
+	"VK_LEFT":                0x25,
+	"VK_UP":                  0x26,
+	"VK_RIGHT":               0x27,
+	"VK_DOWN":                0x28,
+	"VK_SELECT":              0x29,
+	"VK_PRINT":               0x2A,
+	"VK_EXECUTE":             0x2B,
+	"VK_SNAPSHOT":            0x2C,
+	"VK_INSERT":              0x2D,
+	"VK_DELETE":              0x2E,
+	"VK_HELP":                0x2F,
+	"VK_LWIN":                0x5B,
+	"VK_RWIN":                0x5C,
+	"VK_APPS":                0x5D,
+	"VK_SLEEP":               0x5F,
+	"VK_NUMPAD0":             0x60,
+	"VK_NUMPAD1":             0x61,
+	"VK_NUMPAD2":             0x62,
+	"VK_NUMPAD3":             0x63,
+	"VK_NUMPAD4":             0x64,
+	"VK_NUMPAD5":             0x65,
+	"VK_NUMPAD6":             0x66,
+	"VK_NUMPAD7":             0x67,
+	"VK_NUMPAD8":             0x68,
+	"VK_NUMPAD9":             0x69,
+	"VK_MULTIPLY":            0x6A,
+	"VK_ADD":                 0x6B,
+	"VK_SEPARATOR":           0x6C,
+	"VK_SUBTRACT":            0x6D,
+	"VK_DECIMAL":             0x6E,
+	"VK_DIVIDE":              0x6F,
+	"VK_F1":                  0x70,
+	"VK_F2":                  0x71,
+	"VK_F3":                  0x72,
+	"VK_F4":                  0x73,
+	"VK_F5":                  0x74,
+	"VK_F6":                  0x75,
+	"VK_F7":                  0x76,
+	"VK_F8":                  0x77,
+	"VK_F9":                  0x78,
+	"VK_F10":                 0x79,
+	"VK_F11":                 0x7A,
+	"VK_F12":                 0x7B,
+	"VK_F13":                 0x7C,
+	"VK_F14":                 0x7D,
+	"VK_F15":                 0x7E,
+	"VK_F16":                 0x7F,
+	"VK_F17":                 0x80,
+	"VK_F18":                 0x81,
+	"VK_F19":                 0x82,
+	"VK_F20":                 0x83,
+	"VK_F21":                 0x84,
+	"VK_F22":                 0x85,
+	"VK_F23":                 0x86,
+	"VK_F24":                 0x87,
+	"VK_NUMLOCK":             0x90,
+	"VK_SCROLL":              0x91,
+	"VK_BROWSER_BACK":        0xA6,
+	"VK_BROWSER_FORWARD":     0xA7,
+	"VK_BROWSER_REFRESH":     0xA8,
+	"VK_BROWSER_STOP":        0xA9,
+	"VK_BROWSER_SEARCH":      0xAA,
+	"VK_BROWSER_FAVORITES":   0xAB,
+	"VK_BROWSER_HOME":        0xAC,
+	"VK_VOLUME_MUTE":         0xAD,
+	"VK_VOLUME_DOWN":         0xAE,
+	"VK_VOLUME_UP":           0xAF,
+	"VK_MEDIA_NEXT_TRACK":    0xB0,
+	"VK_MEDIA_PREV_TRACK":    0xB1,
+	"VK_MEDIA_STOP":          0xB2,
+	"VK_MEDIA_PLAY_PAUSE":    0xB3,
+	"VK_OEM_1":               0xBA,
+	"VK_OEM_PLUS":            0xBB,
+	"VK_OEM_COMMA":           0xBC,
+	"VK_OEM_MINUS":           0xBD,
+	"VK_OEM_PERIOD":          0xBE,
+	"VK_OEM_2":               0xBF,
+	"VK_OEM_3":               0xC0,
+	"VK_OEM_4":               0xDB,
+	"VK_OEM_5":               0xDC,
+	"VK_OEM_6":               0xDD,
+	"VK_OEM_7":               0xDE,
+	"VK_OEM_102":             0xE2,
+	"VK_LAUNCH_MAIL":         0xB4,
+	"VK_LAUNCH_MEDIA_SELECT": 0xB5,
+	"VK_LAUNCH_APP1":         0xB6,
+	"VK_LAUNCH_APP2":         0xB7,
+}
+
+// loadAccelerators makes an accelerator table from its JSON definition, or loads a binary file.
+func loadAccelerators(dir string, x interface{}) ([]byte, error) {
+	var accels []accelerator
+	switch x := x.(type) {
+	case string:
+		return ioutil.ReadFile(filepath.Join(dir, x))
+	case []accelerator:
+		// Table read from a resource script
+		accels = x
+	case []interface{}:
+		j, _ := json.Marshal(x)
+		err := json.Unmarshal(j, &accels)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New(errInvalidAccelTable)
+	}
+
+	if len(accels) == 0 {
+		return nil, errors.New(errInvalidAccelTable)
+	}
+
+	var errs errorList
+	data := make([]byte, 0, len(accels)*8)
+	for i, a := range accels {
+		flags, key, err := a.encode()
+		if err != nil {
+			errs.add(atPath(err, strconv.Itoa(i)))
+			continue
+		}
+		if i == len(accels)-1 {
+			flags |= accelLast
+		}
+		data = appendUint16(data, flags)
+		data = appendUint16(data, key)
+		data = appendUint16(data, a.ID)
+		data = appendUint16(data, 0)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return data, nil
+}
+
+func (a accelerator) encode() (flags uint16, key uint16, err error) {
+	for _, m := range strings.Split(a.Modifiers, ",") {
+		switch strings.ToLower(strings.TrimSpace(m)) {
+		case "":
+		case "ctrl", "control":
+			flags |= accelControl
+		case "alt":
+			flags |= accelAlt
+		case "shift":
+			flags |= accelShift
+		default:
+			return 0, 0, fmt.Errorf("%s: %q", errUnknownModifier, m)
+		}
+	}
+	if a.NoInvert {
+		flags |= accelNoInvert
+	}
+
+	switch strings.ToLower(a.Type) {
+	case "", keyTypeVirtKey:
+		flags |= accelVirtKey
+		key, err = parseVirtKey(a.Key)
+	case keyTypeASCII:
+		if flags&(accelControl|accelShift) != 0 {
+			return 0, 0, errors.New(errASCIIModifier)
+		}
+		key, err = parseASCIIKey(a.Key)
+	default:
+		return 0, 0, fmt.Errorf("unknown key type %q", a.Type)
+	}
+
+	return flags, key, err
+}
+
+// parseVirtKey accepts a virtual key name, with or without its "VK_" prefix,
+// a letter or a digit, or a number.
+func parseVirtKey(s string) (uint16, error) {
+	name := strings.ToUpper(s)
+	if len(name) == 1 && (name[0] >= 'A' && name[0] <= 'Z' || name[0] >= '0' && name[0] <= '9') {
+		return uint16(name[0]), nil
+	}
+	if vk, ok := virtKeys[name]; ok {
+		return vk, nil
+	}
+	if vk, ok := virtKeys["VK_"+name]; ok {
+		return vk, nil
+	}
+	n, err := strconv.ParseUint(s, 0, 8)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("%s: %q", errInvalidKey, s)
+	}
+	return uint16(n), nil
+}
+
+// parseASCIIKey accepts a single character, or a control character written as "^C".
+func parseASCIIKey(s string) (uint16, error) {
+	u := utf16.Encode([]rune(s))
+	switch {
+	case len(u) == 1:
+		return u[0], nil
+	case len(u) == 2 && u[0] == '^' && u[1] >= '@' && u[1] <= '_':
+		return u[1] - '@', nil
+	case len(u) == 2 && u[0] == '^' && u[1] >= 'a' && u[1] <= 'z':
+		return u[1] - 'a' + 1, nil
+	}
+	return 0, fmt.Errorf("%s: %q", errInvalidKey, s)
+}
+
+// readAccelerators decodes an accelerator table.
+func readAccelerators(data []byte) ([]accelerator, error) {
+	var accels []accelerator
+	for pos := 0; pos+8 <= len(data); pos += 8 {
+		flags := binary.LittleEndian.Uint16(data[pos:])
+		key := binary.LittleEndian.Uint16(data[pos+2:])
+		a := accelerator{
+			ID:       binary.LittleEndian.Uint16(data[pos+4:]),
+			NoInvert: flags&accelNoInvert != 0,
+		}
+
+		var mods []string
+		if flags&accelControl != 0 {
+			mods = append(mods, "Ctrl")
+		}
+		if flags&accelAlt != 0 {
+			mods = append(mods, "Alt")
+		}
+		if flags&accelShift != 0 {
+			mods = append(mods, "Shift")
+		}
+		a.Modifiers = strings.Join(mods, ",")
+
+		if flags&accelVirtKey != 0 {
+			a.Key = virtKeyName(key)
+		} else {
+			a.Type = keyTypeASCII
+			if key < 0x20 {
+				a.Key = "^" + string(rune(key+'@'))
+			} else {
+				a.Key = string(utf16.Decode([]uint16{key}))
+			}
+		}
+
+		accels = append(accels, a)
+		if flags&accelLast != 0 {
+			return accels, nil
+		}
+	}
+
+	return nil, errors.New(errInvalidAccelTable)
+}
+
+func virtKeyName(vk uint16) string {
+	if vk >= 'A' && vk <= 'Z' || vk >= '0' && vk <= '9' {
+		return string(rune(vk))
+	}
+	for name, code := range virtKeys {
+		if code == vk {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%02X", vk)
+}
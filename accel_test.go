@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_loadAccelerators(t *testing.T) {
+	x := []interface{}{
+		map[string]interface{}{"key": "F5", "id": 100.0},
+		map[string]interface{}{"key": "s", "modifiers": "Ctrl", "id": 101.0},
+		map[string]interface{}{"key": "VK_DELETE", "modifiers": "ctrl, shift", "noinvert": true, "id": 102.0},
+		map[string]interface{}{"key": "a", "type": "ascii", "modifiers": "Alt", "id": 103.0},
+		map[string]interface{}{"key": "^C", "type": "ascii", "id": 104.0},
+		map[string]interface{}{"key": "0xE5", "id": 105.0},
+	}
+	data, err := loadAccelerators("", x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x01, 0, 0x74, 0, 100, 0, 0, 0,
+		0x09, 0, 'S', 0, 101, 0, 0, 0,
+		0x0F, 0, 0x2E, 0, 102, 0, 0, 0,
+		0x10, 0, 'a', 0, 103, 0, 0, 0,
+		0x00, 0, 0x03, 0, 104, 0, 0, 0,
+		0x81, 0, 0xE5, 0, 105, 0, 0, 0,
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("loadAccelerators() = %v, want %v", data, want)
+	}
+
+	accels, err := readAccelerators(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAccels := []accelerator{
+		{Key: "VK_F5", ID: 100},
+		{Key: "S", Modifiers: "Ctrl", ID: 101},
+		{Key: "VK_DELETE", Modifiers: "Ctrl,Shift", NoInvert: true, ID: 102},
+		{Key: "a", Type: "ascii", Modifiers: "Alt", ID: 103},
+		{Key: "^C", Type: "ascii", ID: 104},
+		{Key: "0xE5", ID: 105},
+	}
+	if !reflect.DeepEqual(accels, wantAccels) {
+		t.Errorf("readAccelerators() = %v, want %v", accels, wantAccels)
+	}
+}
+
+func Test_loadAccelerators_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		x    interface{}
+	}{
+		{"object", map[string]interface{}{}},
+		{"empty", []interface{}{}},
+		{"key", []interface{}{map[string]interface{}{"key": "F99", "id": 1.0}}},
+		{"no key", []interface{}{map[string]interface{}{"id": 1.0}}},
+		{"ascii key", []interface{}{map[string]interface{}{"key": "ab", "type": "ascii", "id": 1.0}}},
+		{"ascii modifier", []interface{}{map[string]interface{}{"key": "a", "type": "ascii", "modifiers": "Ctrl", "id": 1.0}}},
+		{"modifier", []interface{}{map[string]interface{}{"key": "A", "modifiers": "Meta", "id": 1.0}}},
+		{"type", []interface{}{map[string]interface{}{"key": "A", "type": "scan", "id": 1.0}}},
+		{"id", []interface{}{map[string]interface{}{"key": "A", "id": 65536.0}}},
+		{"file", "missing.bin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadAccelerators("_testdata", tt.x); err == nil {
+				t.Error("loadAccelerators() should fail")
+			}
+		})
+	}
+}
+
+func Test_readAccelerators(t *testing.T) {
+	if _, err := readAccelerators([]byte{1, 0, 'A', 0, 1, 0, 0, 0}); err == nil {
+		t.Error("readAccelerators() should fail without a last entry")
+	}
+	if _, err := readAccelerators([]byte{0x80, 0, 'A', 0, 1, 0}); err == nil {
+		t.Error("readAccelerators() should fail on truncated data")
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errInvalidAnimation = "invalid animation definition"
+	errNoFrames         = "an animation needs at least one frame"
+)
+
+// Default display rate, in jiffies (1/60 s)
+const defaultAniRate = 10
+
+const (
+	afIcon     = 0x1
+	afSequence = 0x2
+)
+
+// animation is the JSON form of an animated cursor or an animated icon.
+type animation struct {
+	Rate     uint32        `json:"rate,omitempty"`
+	Frames   []interface{} `json:"frames"`
+	Sequence []uint32      `json:"sequence,omitempty"`
+}
+
+// aniFrame is a frame of an animation.
+// Its image can be a .cur or .ico file, or any image, with a hot spot for cursors.
+type aniFrame struct {
+	Image string `json:"image"`
+	Rate  uint32 `json:"rate,omitempty"`
+	// hotSpot is read from "x" and "y", or "hotspot", like the hot spot of a cursor image.
+	// It is nil when not defined, which means the top left pixel.
+	hotSpot *hotSpotDef
+}
+
+// loadAnimation makes an .ani file from its JSON definition, or loads an existing .ani file.
+func loadAnimation(dir string, x interface{}, cursor bool) ([]byte, error) {
+	switch x := x.(type) {
+	case string:
+		return ioutil.ReadFile(filepath.Join(dir, x))
+	case map[string]interface{}:
+	default:
+		return nil, errors.New(errInvalidAnimation)
+	}
+
+	ani := animation{}
+	j, _ := json.Marshal(x)
+	err := json.Unmarshal(j, &ani)
+	if err != nil {
+		return nil, err
+	}
+	if ani.Rate == 0 {
+		ani.Rate = defaultAniRate
+	}
+
+	var (
+		frames []aniFrame
+		errs   errorList
+	)
+	for i, f := range ani.Frames {
+		var frame aniFrame
+		switch f := f.(type) {
+		case string:
+			frame.Image = f
+		case map[string]interface{}:
+			j, _ := json.Marshal(f)
+			err = json.Unmarshal(j, &frame)
+			if err == nil && frame.Image == "" {
+				err = errors.New(errInvalidAnimation)
+			}
+			if err == nil {
+				frame.hotSpot, err = readHotSpot(f)
+			}
+		default:
+			err = errors.New(errInvalidAnimation)
+		}
+		if err != nil {
+			errs.add(atPath(err, "frames", strconv.Itoa(i)))
+			continue
+		}
+		if frame.Rate == 0 {
+			frame.Rate = ani.Rate
+		}
+		frames = append(frames, frame)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return ani.bytes(dir, frames, cursor)
+}
+
+// bytes writes a RIFF ACON file.
+//
+// The "anih" header is followed by optional "rate" and "seq " chunks, and a "fram" list of icons.
+func (ani *animation) bytes(dir string, frames []aniFrame, cursor bool) ([]byte, error) {
+	if len(frames) == 0 {
+		return nil, errors.New(errNoFrames)
+	}
+
+	var errs errorList
+	seq := ani.Sequence
+	for i, n := range seq {
+		if int(n) >= len(frames) {
+			errs.add(atPath(fmt.Errorf("frame %d does not exist", n), "sequence", strconv.Itoa(i)))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if len(seq) == 0 {
+		for i := range frames {
+			seq = append(seq, uint32(i))
+		}
+	}
+
+	rates := make([]uint32, len(seq))
+	sameRate := true
+	for i, n := range seq {
+		rates[i] = frames[n].Rate
+		if rates[i] != ani.Rate {
+			sameRate = false
+		}
+	}
+
+	fram := &bytes.Buffer{}
+	fram.WriteString("fram")
+	for i, f := range frames {
+		data, err := loadAniFrame(dir, f, cursor)
+		if err != nil {
+			errs.add(atPath(err, "frames", strconv.Itoa(i)))
+			continue
+		}
+		writeChunk(fram, "icon", data)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	attr := uint32(afIcon)
+	if len(ani.Sequence) > 0 {
+		attr |= afSequence
+	}
+
+	acon := &bytes.Buffer{}
+	acon.WriteString("ACON")
+
+	anih := &bytes.Buffer{}
+	binary.Write(anih, binary.LittleEndian, struct {
+		Size      uint32
+		Frames    uint32
+		Steps     uint32
+		Width     uint32
+		Height    uint32
+		BitCount  uint32
+		Planes    uint32
+		Rate      uint32
+		Attribute uint32
+	}{36, uint32(len(frames)), uint32(len(seq)), 0, 0, 0, 0, ani.Rate, attr})
+	writeChunk(acon, "anih", anih.Bytes())
+
+	if !sameRate {
+		writeChunk(acon, "rate", dwords(rates))
+	}
+	if len(ani.Sequence) > 0 {
+		writeChunk(acon, "seq ", dwords(seq))
+	}
+	writeChunk(acon, "LIST", fram.Bytes())
+
+	riff := &bytes.Buffer{}
+	writeChunk(riff, "RIFF", acon.Bytes())
+
+	return riff.Bytes(), nil
+}
+
+// loadAniFrame returns the content of a .cur or .ico file for a frame.
+func loadAniFrame(dir string, f aniFrame, cursor bool) ([]byte, error) {
+	if f.Image == "" {
+		return nil, errors.New(errInvalidAnimation)
+	}
+
+	name := filepath.Join(dir, f.Image)
+	buf := &bytes.Buffer{}
+
+	switch strings.ToLower(filepath.Ext(f.Image)) {
+	case ".cur":
+		cur, err := loadCUR(name)
+		if err != nil {
+			return nil, err
+		}
+		err = cur.SaveCUR(buf)
+		return buf.Bytes(), err
+	case ".ico":
+		icon, err := loadICO(name)
+		if err != nil {
+			return nil, err
+		}
+		err = icon.SaveICO(buf)
+		return buf.Bytes(), err
+	}
+
+	img, err := loadImage(name)
+	if err != nil {
+		return nil, err
+	}
+	if cursor {
+		var hs winres.HotSpot
+		if f.hotSpot != nil {
+			f.hotSpot.size = img.Bounds().Size()
+			hs, err = f.hotSpot.at(img.Bounds().Size())
+			if err != nil {
+				return nil, err
+			}
+		}
+		cur, err := winres.NewCursorFromImages([]winres.CursorImage{{
+			Image:   img,
+			HotSpot: hs,
+		}})
+		if err != nil {
+			return nil, err
+		}
+		err = cur.SaveCUR(buf)
+		return buf.Bytes(), err
+	}
+	icon, err := winres.NewIconFromImages([]image.Image{img})
+	if err != nil {
+		return nil, err
+	}
+	err = icon.SaveICO(buf)
+	return buf.Bytes(), err
+}
+
+// writeChunk writes a RIFF chunk, padded to an even size.
+func writeChunk(buf *bytes.Buffer, id string, data []byte) {
+	buf.WriteString(id)
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	if len(data)%2 != 0 {
+		buf.WriteByte(0)
+	}
+}
+
+func dwords(values []uint32) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, values)
+	return buf.Bytes()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_loadAnimation(t *testing.T) {
+	x := map[string]interface{}{
+		"rate": 6.0,
+		"frames": []interface{}{
+			map[string]interface{}{"image": "cur-32x64.png", "x": 16.0, "y": 8.0},
+			map[string]interface{}{"image": "cur-64x128.png", "rate": 12.0},
+			"cursor.cur",
+		},
+		"sequence": []interface{}{0.0, 1.0, 2.0, 1.0},
+	}
+
+	data, err := loadAnimation("_testdata", x, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "ACON" || int(binary.LittleEndian.Uint32(data[4:]))+8 != len(data) {
+		t.Fatal("not a RIFF ACON file")
+	}
+	chunks := readChunks(t, data[12:])
+
+	var anih struct {
+		Size, Frames, Steps, Width, Height, BitCount, Planes, Rate, Attribute uint32
+	}
+	binary.Read(bytes.NewReader(chunks["anih"]), binary.LittleEndian, &anih)
+	if anih.Size != 36 || anih.Frames != 3 || anih.Steps != 4 || anih.Rate != 6 || anih.Attribute != afIcon|afSequence {
+		t.Errorf("wrong header %+v", anih)
+	}
+	if !bytes.Equal(chunks["rate"], dwords([]uint32{6, 12, 6, 12})) {
+		t.Errorf("wrong rates %v", chunks["rate"])
+	}
+	if !bytes.Equal(chunks["seq "], dwords([]uint32{0, 1, 2, 1})) {
+		t.Errorf("wrong sequence %v", chunks["seq "])
+	}
+
+	fram := chunks["LIST"]
+	if string(fram[:4]) != "fram" {
+		t.Fatal("missing frame list")
+	}
+	frames := 0
+	for pos := 4; pos < len(fram); {
+		size := int(binary.LittleEndian.Uint32(fram[pos+4:]))
+		if string(fram[pos:pos+4]) != "icon" {
+			t.Fatalf("unexpected chunk %q", fram[pos:pos+4])
+		}
+		cur, err := winres.LoadCUR(bytes.NewReader(fram[pos+8 : pos+8+size]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frames == 0 {
+			buf := &bytes.Buffer{}
+			cur.SaveCUR(buf)
+			// Hot spot of the first image in the CUR header
+			if hs := buf.Bytes()[10:14]; !reflect.DeepEqual(hs, []byte{16, 0, 8, 0}) {
+				t.Errorf("wrong hot spot %v", hs)
+			}
+		}
+		frames++
+		pos += 8 + (size+1)&^1
+	}
+	if frames != 3 {
+		t.Errorf("found %d frames, want 3", frames)
+	}
+}
+
+func Test_loadAnimation_Icon(t *testing.T) {
+	data, err := loadAnimation("_testdata", map[string]interface{}{
+		"frames": []interface{}{"cur-32x64.png", "en.ico"},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := readChunks(t, data[12:])
+	if _, ok := chunks["rate"]; ok {
+		t.Error("rate chunk should be omitted when all rates are the default")
+	}
+	if _, ok := chunks["seq "]; ok {
+		t.Error("seq chunk should be omitted without a sequence")
+	}
+	if binary.LittleEndian.Uint32(chunks["anih"][28:]) != defaultAniRate || binary.LittleEndian.Uint32(chunks["anih"][32:]) != afIcon {
+		t.Errorf("wrong header %v", chunks["anih"])
+	}
+}
+
+func Test_loadAnimation_HotSpot(t *testing.T) {
+	data, err := loadAnimation("_testdata", map[string]interface{}{
+		"frames": []interface{}{map[string]interface{}{"image": "cur-32x64.png", "hotspot": "center"}},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fram := readChunks(t, data[12:])["LIST"]
+	// Hot spot of the first image in the CUR header of the first frame
+	if hs := fram[4+8+10 : 4+8+14]; !reflect.DeepEqual(hs, []byte{16, 0, 32, 0}) {
+		t.Errorf("wrong hot spot %v", hs)
+	}
+}
+
+func Test_loadAnimation_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		x    interface{}
+		err  string
+	}{
+		{"not an object", 42.0, errInvalidAnimation},
+		{"no frames", map[string]interface{}{}, errNoFrames},
+		{"bad frame", map[string]interface{}{"frames": []interface{}{42.0}}, "[frames][0] " + errInvalidAnimation},
+		{"no image", map[string]interface{}{"frames": []interface{}{map[string]interface{}{"x": 1.0}}}, "[frames][0] " + errInvalidAnimation},
+		{"bad hot spot", map[string]interface{}{"frames": []interface{}{map[string]interface{}{"image": "cur-32x64.png", "hotspot": "middle"}}}, "[frames][0] " + errInvalidHotSpot + `: "middle"`},
+		{"hot spot outside", map[string]interface{}{"frames": []interface{}{map[string]interface{}{"image": "cur-32x64.png", "x": 32.0, "y": 0.0}}}, "[frames][0] " + errHotSpotOutside + ": (32, 0) is not in 32x64"},
+		{"bad sequence", map[string]interface{}{"frames": []interface{}{"en.ico"}, "sequence": []interface{}{0.0, 1.0}}, "[sequence][1] frame 1 does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadAnimation("_testdata", tt.x, true)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("loadAnimation() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func readChunks(t *testing.T, data []byte) map[string][]byte {
+	chunks := make(map[string][]byte)
+	for pos := 0; pos < len(data); {
+		if pos+8 > len(data) {
+			t.Fatal("truncated chunk")
+		}
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		chunks[string(data[pos:pos+4])] = data[pos+8 : pos+8+size]
+		pos += 8 + (size+1)&^1
+	}
+	return chunks
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errInvalidCOFF = "invalid object file"
+	errInvalidRSRC = "invalid resource directory"
+)
+
+// Relocation types that give the RVA of a symbol.
+// These are the only ones a resource section should need.
+//
+// https://docs.microsoft.com/en-us/windows/win32/debug/pe-format#type-indicators
+var addr32NB = map[uint16]uint16{
+	pe.IMAGE_FILE_MACHINE_I386:  0x7,
+	pe.IMAGE_FILE_MACHINE_AMD64: 0x3,
+	pe.IMAGE_FILE_MACHINE_ARMNT: 0x2,
+	pe.IMAGE_FILE_MACHINE_ARM64: 0x2,
+}
+
+// isCOFF tells if data looks like a COFF object file, such as a syso file.
+func isCOFF(data []byte) bool {
+	if len(data) < 20 {
+		return false
+	}
+	_, ok := addr32NB[binary.LittleEndian.Uint16(data)]
+	// Object files have no optional header
+	return ok && binary.LittleEndian.Uint16(data[16:]) == 0
+}
+
+// readCOFF adds the resources of a COFF object file to a resource set.
+//
+// The resource directory can be split in several sections (.rsrc$01, .rsrc$02, ...)
+// like the output of cvtres.exe, or stand in a single .rsrc section
+// like the output of go-winres or rsrc.
+// Sections are laid out the way a linker would, then relocations are applied.
+func readCOFF(rs *winres.ResourceSet, data []byte) error {
+	f, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %v", errInvalidCOFF, err)
+	}
+
+	var sections []int
+	for i, s := range f.Sections {
+		if s.Name == ".rsrc" || strings.HasPrefix(s.Name, ".rsrc$") {
+			sections = append(sections, i)
+		}
+	}
+	if len(sections) == 0 {
+		return winres.ErrNoResources
+	}
+	sort.SliceStable(sections, func(i, j int) bool {
+		return f.Sections[sections[i]].Name < f.Sections[sections[j]].Name
+	})
+
+	var rsrc []byte
+	base := make(map[int]uint32)
+	for _, i := range sections {
+		b, err := f.Sections[i].Data()
+		if err != nil {
+			return fmt.Errorf("%s: %v", errInvalidCOFF, err)
+		}
+		for len(rsrc)%8 != 0 {
+			rsrc = append(rsrc, 0)
+		}
+		base[i] = uint32(len(rsrc))
+		rsrc = append(rsrc, b...)
+	}
+
+	for _, i := range sections {
+		for _, r := range f.Sections[i].Relocs {
+			if r.Type != addr32NB[f.Machine] || int(r.SymbolTableIndex) >= len(f.COFFSymbols) {
+				return errors.New(errInvalidCOFF)
+			}
+			sym := f.COFFSymbols[r.SymbolTableIndex]
+			target, ok := base[int(sym.SectionNumber)-1]
+			if !ok {
+				return errors.New(errInvalidCOFF)
+			}
+			pos := int(base[i]) + int(r.VirtualAddress)
+			if pos+4 > len(rsrc) {
+				return errors.New(errInvalidCOFF)
+			}
+			addr := binary.LittleEndian.Uint32(rsrc[pos:])
+			binary.LittleEndian.PutUint32(rsrc[pos:], addr+target+sym.Value)
+		}
+	}
+
+	return readRSRC(rs, rsrc)
+}
+
+type rsrcEntry struct {
+	id     winres.Identifier
+	offset uint32
+	isDir  bool
+}
+
+// readRSRC adds the resources of a resource section to a resource set.
+// Addresses in data entries must be offsets in the section.
+//
+// https://docs.microsoft.com/en-us/windows/win32/debug/pe-format#the-rsrc-section
+func readRSRC(rs *winres.ResourceSet, section []byte) error {
+	types, err := readRSRCDir(section, 0)
+	if err != nil {
+		return err
+	}
+	for _, t := range types {
+		if !t.isDir {
+			return errors.New(errInvalidRSRC)
+		}
+		names, err := readRSRCDir(section, t.offset)
+		if err != nil {
+			return err
+		}
+		for _, r := range names {
+			if !r.isDir {
+				return errors.New(errInvalidRSRC)
+			}
+			langs, err := readRSRCDir(section, r.offset)
+			if err != nil {
+				return err
+			}
+			for _, l := range langs {
+				langID, ok := l.id.(winres.ID)
+				if !ok || l.isDir || int(l.offset)+16 > len(section) {
+					return errors.New(errInvalidRSRC)
+				}
+				addr := binary.LittleEndian.Uint32(section[l.offset:])
+				size := binary.LittleEndian.Uint32(section[l.offset+4:])
+				if uint64(addr)+uint64(size) > uint64(len(section)) {
+					return errors.New(errInvalidRSRC)
+				}
+				err = rs.Set(t.id, r.id, uint16(langID), append([]byte{}, section[addr:addr+size]...))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func readRSRCDir(section []byte, offset uint32) ([]rsrcEntry, error) {
+	pos := int(offset)
+	if pos+16 > len(section) {
+		return nil, errors.New(errInvalidRSRC)
+	}
+	count := int(binary.LittleEndian.Uint16(section[pos+12:])) + int(binary.LittleEndian.Uint16(section[pos+14:]))
+	pos += 16
+	if pos+count*8 > len(section) {
+		return nil, errors.New(errInvalidRSRC)
+	}
+
+	entries := make([]rsrcEntry, count)
+	for i := range entries {
+		id := binary.LittleEndian.Uint32(section[pos:])
+		off := binary.LittleEndian.Uint32(section[pos+4:])
+		pos += 8
+
+		if id&0x80000000 != 0 {
+			name, err := readRSRCName(section, id&0x7FFFFFFF)
+			if err != nil {
+				return nil, err
+			}
+			entries[i].id = name
+		} else {
+			entries[i].id = winres.ID(id)
+		}
+		entries[i].offset = off & 0x7FFFFFFF
+		entries[i].isDir = off&0x80000000 != 0
+	}
+
+	return entries, nil
+}
+
+func readRSRCName(section []byte, offset uint32) (winres.Name, error) {
+	pos := int(offset)
+	if pos+2 > len(section) {
+		return "", errors.New(errInvalidRSRC)
+	}
+	n := int(binary.LittleEndian.Uint16(section[pos:]))
+	pos += 2
+	if pos+n*2 > len(section) {
+		return "", errors.New(errInvalidRSRC)
+	}
+	u := make([]uint16, n)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(section[pos+i*2:])
+	}
+	return winres.Name(utf16.Decode(u)), nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_readCOFF(t *testing.T) {
+	f, err := os.Open(filepath.Join("_testdata", "rh.exe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rs, err := winres.LoadFromEXE(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, arch := range []winres.Arch{winres.ArchI386, winres.ArchAMD64, winres.ArchARM, winres.ArchARM64} {
+		t.Run(string(arch), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := rs.WriteObject(buf, arch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !isCOFF(buf.Bytes()) {
+				t.Fatal("isCOFF returned false")
+			}
+
+			rs2 := &winres.ResourceSet{}
+			err = readCOFF(rs2, buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rs2.Count() != rs.Count() {
+				t.Errorf("wrong number of resources (%d, want %d)", rs2.Count(), rs.Count())
+			}
+			rs.Walk(func(typeID, resID winres.Identifier, langID uint16, data []byte) bool {
+				if !bytes.Equal(rs2.Get(typeID, resID, langID), data) {
+					t.Errorf("[%v][%v][%04X] differs", typeID, resID, langID)
+				}
+				return true
+			})
+		})
+	}
+}
+
+// cvtres.obj was made by llvm-cvtres, which splits resources in .rsrc$01 and .rsrc$02,
+// with one symbol per resource.
+func Test_readCOFF_CVTRES(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("_testdata", "cvtres.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isCOFF(data) {
+		t.Fatal("isCOFF returned false")
+	}
+
+	rs := &winres.ResourceSet{}
+	err = readCOFF(rs, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(rs.Get(winres.RT_RCDATA, winres.Name("DATA"), 0x40C)) != "hello\x00" {
+		t.Errorf("wrong RCDATA: %v", rs.Get(winres.RT_RCDATA, winres.Name("DATA"), 0x40C))
+	}
+	if !bytes.Equal(rs.Get(winres.RT_RCDATA, winres.ID(7), 0x40C), []byte{1, 0, 2, 0, 3, 0}) {
+		t.Errorf("wrong RCDATA: %v", rs.Get(winres.RT_RCDATA, winres.ID(7), 0x40C))
+	}
+	if rs.Get(winres.RT_STRING, winres.ID(1), 0x40C) == nil {
+		t.Error("missing string table")
+	}
+}
+
+func Test_readCOFF_Errors(t *testing.T) {
+	rs := &winres.ResourceSet{}
+	rs.Set(winres.RT_RCDATA, winres.ID(1), 0x409, []byte{1, 2, 3})
+	buf := &bytes.Buffer{}
+	rs.WriteObject(buf, winres.ArchAMD64)
+	valid := buf.Bytes()
+
+	noRSRC := append([]byte{}, valid...)
+	copy(noRSRC[20:], ".data")
+
+	badReloc := append([]byte{}, valid...)
+	// Relocation type of the only data entry
+	badReloc[len(badReloc)-4-18-2] = 0x1
+
+	if isCOFF([]byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xFF\xFF\x00\x00\xB8\x00\x00\x00")) {
+		t.Error("isCOFF returned true for an executable")
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"truncated", valid[:30], ""},
+		{"no rsrc", noRSRC, winres.ErrNoResources.Error()},
+		{"bad reloc", badReloc, errInvalidCOFF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := readCOFF(&winres.ResourceSet{}, tt.data)
+			if err == nil || tt.err != "" && err.Error() != tt.err {
+				t.Errorf("readCOFF() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_listResources(t *testing.T) {
+	rs := &winres.ResourceSet{}
+	rs.Set(winres.RT_RCDATA, winres.Name("DATA"), 0x409, []byte{1, 2, 3})
+	rs.Set(winres.Name("MY_TYPE"), winres.ID(42), 0x40C, make([]byte, 1000))
+
+	buf := &bytes.Buffer{}
+	listResources(buf, rs)
+
+	want := "TYPE       NAME  LANG  SIZE\n" +
+		"MY_TYPE    #42   040C  1000\n" +
+		"RT_RCDATA  DATA  0409  3\n"
+	if buf.String() != want {
+		t.Errorf("listResources() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,671 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	errInvalidDialog = "invalid dialog template"
+	errUnknownStyle  = "unknown style"
+	errMissingFont   = "DS_SETFONT requires a font"
+	errTooManyItems  = "too many controls"
+)
+
+// dialog is the JSON form of a DIALOGEX template.
+//
+// https://docs.microsoft.com/en-us/windows/win32/dlgbox/dlgtemplateex
+type dialog struct {
+	X        int16           `json:"x"`
+	Y        int16           `json:"y"`
+	Width    int16           `json:"width"`
+	Height   int16           `json:"height"`
+	Title    string          `json:"title,omitempty"`
+	Style    string          `json:"style,omitempty"`
+	ExStyle  string          `json:"ex_style,omitempty"`
+	HelpID   uint32          `json:"help_id,omitempty"`
+	Menu     string          `json:"menu,omitempty"`
+	Class    string          `json:"class,omitempty"`
+	Font     *dialogFont     `json:"font,omitempty"`
+	Controls []dialogControl `json:"controls,omitempty"`
+}
+
+type dialogFont struct {
+	Name    string `json:"name"`
+	Size    uint16 `json:"size"`
+	Weight  uint16 `json:"weight,omitempty"`
+	Italic  bool   `json:"italic,omitempty"`
+	Charset uint8  `json:"charset,omitempty"`
+}
+
+// dialogControl is the JSON form of a DIALOGEX item.
+//
+// https://docs.microsoft.com/en-us/windows/win32/dlgbox/dlgitemtemplateex
+type dialogControl struct {
+	Class   string `json:"class"`
+	Text    string `json:"text,omitempty"`
+	ID      uint32 `json:"id,omitempty"`
+	X       int16  `json:"x"`
+	Y       int16  `json:"y"`
+	Width   int16  `json:"width"`
+	Height  int16  `json:"height"`
+	Style   string `json:"style,omitempty"`
+	ExStyle string `json:"ex_style,omitempty"`
+	HelpID  uint32 `json:"help_id,omitempty"`
+	Data    []byte `json:"data,omitempty"`
+}
+
+type style struct {
+	name  string
+	value uint32
+}
+
+const (
+	dsSetFont = 0x40
+	wsGroup   = 0x00020000
+	wsTabStop = 0x00010000
+)
+
+// Window styles, in the order they are looked for when decoding a style.
+// https://docs.microsoft.com/en-us/windows/win32/winmsg/window-styles
+var windowStyles = []style{
+	{"WS_POPUP", 0x80000000},
+	{"WS_CHILD", 0x40000000},
+	{"WS_MINIMIZE", 0x20000000},
+	{"WS_VISIBLE", 0x10000000},
+	{"WS_DISABLED", 0x08000000},
+	{"WS_CLIPSIBLINGS", 0x04000000},
+	{"WS_CLIPCHILDREN", 0x02000000},
+	{"WS_MAXIMIZE", 0x01000000},
+	{"WS_CAPTION", 0x00C00000},
+	{"WS_BORDER", 0x00800000},
+	{"WS_DLGFRAME", 0x00400000},
+	{"WS_VSCROLL", 0x00200000},
+	{"WS_HSCROLL", 0x00100000},
+	{"WS_SYSMENU", 0x00080000},
+	{"WS_THICKFRAME", 0x00040000},
+}
+
+// Dialog box styles
+// https://docs.microsoft.com/en-us/windows/win32/dlgbox/dialog-box-styles
+var dialogStyles = []style{
+	{"WS_MINIMIZEBOX", wsGroup},
+	{"WS_MAXIMIZEBOX", wsTabStop},
+	{"DS_SHELLFONT", 0x48},
+	{"DS_ABSALIGN", 0x01},
+	{"DS_SYSMODAL", 0x02},
+	{"DS_3DLOOK", 0x04},
+	{"DS_FIXEDSYS", 0x08},
+	{"DS_NOFAILCREATE", 0x10},
+	{"DS_LOCALEDIT", 0x20},
+	{"DS_SETFONT", dsSetFont},
+	{"DS_MODALFRAME", 0x80},
+	{"DS_NOIDLEMSG", 0x100},
+	{"DS_SETFOREGROUND", 0x200},
+	{"DS_CONTROL", 0x400},
+	{"DS_CENTER", 0x800},
+	{"DS_CENTERMOUSE", 0x1000},
+	{"DS_CONTEXTHELP", 0x2000},
+}
+
+var controlStyles = []style{
+	{"WS_GROUP", wsGroup},
+	{"WS_TABSTOP", wsTabStop},
+}
+
+// Common control styles, only used when parsing.
+// When decoding, the class specific part of a style is written as a number.
+var classStyles = []style{
+	{"BS_PUSHBUTTON", 0x0},
+	{"BS_DEFPUSHBUTTON", 0x1},
+	{"BS_CHECKBOX", 0x2},
+	{"BS_AUTOCHECKBOX", 0x3},
+	{"BS_RADIOBUTTON", 0x4},
+	{"BS_3STATE", 0x5},
+	{"BS_AUTO3STATE", 0x6},
+	{"BS_GROUPBOX", 0x7},
+	{"BS_AUTORADIOBUTTON", 0x9},
+	{"BS_PUSHBOX", 0xA},
+	{"BS_OWNERDRAW", 0xB},
+	{"BS_LEFTTEXT", 0x20},
+	{"BS_ICON", 0x40},
+	{"BS_BITMAP", 0x80},
+	{"BS_LEFT", 0x100},
+	{"BS_RIGHT", 0x200},
+	{"BS_CENTER", 0x300},
+	{"BS_TOP", 0x400},
+	{"BS_BOTTOM", 0x800},
+	{"BS_VCENTER", 0xC00},
+	{"BS_PUSHLIKE", 0x1000},
+	{"BS_MULTILINE", 0x2000},
+	{"BS_NOTIFY", 0x4000},
+	{"BS_FLAT", 0x8000},
+	{"ES_LEFT", 0x0},
+	{"ES_CENTER", 0x1},
+	{"ES_RIGHT", 0x2},
+	{"ES_MULTILINE", 0x4},
+	{"ES_UPPERCASE", 0x8},
+	{"ES_LOWERCASE", 0x10},
+	{"ES_PASSWORD", 0x20},
+	{"ES_AUTOVSCROLL", 0x40},
+	{"ES_AUTOHSCROLL", 0x80},
+	{"ES_NOHIDESEL", 0x100},
+	{"ES_OEMCONVERT", 0x400},
+	{"ES_READONLY", 0x800},
+	{"ES_WANTRETURN", 0x1000},
+	{"ES_NUMBER", 0x2000},
+	{"SS_LEFT", 0x0},
+	{"SS_CENTER", 0x1},
+	{"SS_RIGHT", 0x2},
+	{"SS_ICON", 0x3},
+	{"SS_BLACKRECT", 0x4},
+	{"SS_GRAYRECT", 0x5},
+	{"SS_WHITERECT", 0x6},
+	{"SS_BLACKFRAME", 0x7},
+	{"SS_GRAYFRAME", 0x8},
+	{"SS_WHITEFRAME", 0x9},
+	{"SS_SIMPLE", 0xB},
+	{"SS_LEFTNOWORDWRAP", 0xC},
+	{"SS_OWNERDRAW", 0xD},
+	{"SS_BITMAP", 0xE},
+	{"SS_ETCHEDHORZ", 0x10},
+	{"SS_ETCHEDVERT", 0x11},
+	{"SS_ETCHEDFRAME", 0x12},
+	{"SS_NOPREFIX", 0x80},
+	{"SS_NOTIFY", 0x100},
+	{"SS_CENTERIMAGE", 0x200},
+	{"SS_SUNKEN", 0x1000},
+	{"SS_ENDELLIPSIS", 0x4000},
+	{"SS_PATHELLIPSIS", 0x8000},
+	{"SS_WORDELLIPSIS", 0xC000},
+	{"LBS_NOTIFY", 0x1},
+	{"LBS_SORT", 0x2},
+	{"LBS_NOREDRAW", 0x4},
+	{"LBS_MULTIPLESEL", 0x8},
+	{"LBS_OWNERDRAWFIXED", 0x10},
+	{"LBS_OWNERDRAWVARIABLE", 0x20},
+	{"LBS_HASSTRINGS", 0x40},
+	{"LBS_USETABSTOPS", 0x80},
+	{"LBS_NOINTEGRALHEIGHT", 0x100},
+	{"LBS_MULTICOLUMN", 0x200},
+	{"LBS_WANTKEYBOARDINPUT", 0x400},
+	{"LBS_EXTENDEDSEL", 0x800},
+	{"LBS_DISABLENOSCROLL", 0x1000},
+	{"LBS_NOSEL", 0x4000},
+	{"CBS_SIMPLE", 0x1},
+	{"CBS_DROPDOWN", 0x2},
+	{"CBS_DROPDOWNLIST", 0x3},
+	{"CBS_OWNERDRAWFIXED", 0x10},
+	{"CBS_OWNERDRAWVARIABLE", 0x20},
+	{"CBS_AUTOHSCROLL", 0x40},
+	{"CBS_OEMCONVERT", 0x80},
+	{"CBS_SORT", 0x100},
+	{"CBS_HASSTRINGS", 0x200},
+	{"CBS_NOINTEGRALHEIGHT", 0x400},
+	{"CBS_DISABLENOSCROLL", 0x800},
+	{"CBS_UPPERCASE", 0x2000},
+	{"CBS_LOWERCASE", 0x4000},
+	{"SBS_HORZ", 0x0},
+	{"SBS_VERT", 0x1},
+}
+
+// Extended window styles
+// https://docs.microsoft.com/en-us/windows/win32/winmsg/extended-window-styles
+var exStyles = []style{
+	{"WS_EX_DLGMODALFRAME", 0x00000001},
+	{"WS_EX_NOPARENTNOTIFY", 0x00000004},
+	{"WS_EX_TOPMOST", 0x00000008},
+	{"WS_EX_ACCEPTFILES", 0x00000010},
+	{"WS_EX_TRANSPARENT", 0x00000020},
+	{"WS_EX_MDICHILD", 0x00000040},
+	{"WS_EX_TOOLWINDOW", 0x00000080},
+	{"WS_EX_WINDOWEDGE", 0x00000100},
+	{"WS_EX_CLIENTEDGE", 0x00000200},
+	{"WS_EX_CONTEXTHELP", 0x00000400},
+	{"WS_EX_RIGHT", 0x00001000},
+	{"WS_EX_RTLREADING", 0x00002000},
+	{"WS_EX_LEFTSCROLLBAR", 0x00004000},
+	{"WS_EX_CONTROLPARENT", 0x00010000},
+	{"WS_EX_STATICEDGE", 0x00020000},
+	{"WS_EX_APPWINDOW", 0x00040000},
+	{"WS_EX_LAYERED", 0x00080000},
+	{"WS_EX_NOINHERITLAYOUT", 0x00100000},
+	{"WS_EX_LAYOUTRTL", 0x00400000},
+	{"WS_EX_COMPOSITED", 0x02000000},
+	{"WS_EX_NOACTIVATE", 0x08000000},
+}
+
+// Predefined control classes are stored as atoms.
+var controlClasses = []string{"BUTTON", "EDIT", "STATIC", "LISTBOX", "SCROLLBAR", "COMBOBOX"}
+
+const firstClassAtom = 0x80
+
+// loadDialog makes a DIALOGEX template from its JSON definition, or loads a binary file.
+func loadDialog(dir string, x interface{}) ([]byte, error) {
+	switch x := x.(type) {
+	case string:
+		return ioutil.ReadFile(filepath.Join(dir, x))
+	case *dialog:
+		// Template read from a resource script
+		return x.bytes()
+	case map[string]interface{}:
+	default:
+		return nil, errors.New(errInvalidDialog)
+	}
+
+	dlg := dialog{}
+	j, _ := json.Marshal(x)
+	err := json.Unmarshal(j, &dlg)
+	if err != nil {
+		return nil, err
+	}
+
+	return dlg.bytes()
+}
+
+func (dlg *dialog) bytes() ([]byte, error) {
+	dlgStyle, err := parseStyle(dlg.Style, windowStyles, dialogStyles)
+	if err != nil {
+		return nil, atPath(err, "style")
+	}
+	exStyle, err := parseStyle(dlg.ExStyle, exStyles)
+	if err != nil {
+		return nil, atPath(err, "ex_style")
+	}
+	if dlg.Font != nil {
+		dlgStyle |= dsSetFont
+	} else if dlgStyle&dsSetFont != 0 {
+		return nil, errors.New(errMissingFont)
+	}
+	if len(dlg.Controls) > 0xFFFF {
+		return nil, errors.New(errTooManyItems)
+	}
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, struct {
+		Version   uint16
+		Signature uint16
+		HelpID    uint32
+		ExStyle   uint32
+		Style     uint32
+		Count     uint16
+		X, Y      int16
+		CX, CY    int16
+	}{1, 0xFFFF, dlg.HelpID, exStyle, dlgStyle, uint16(len(dlg.Controls)), dlg.X, dlg.Y, dlg.Width, dlg.Height})
+	writeSzOrOrd(buf, dlg.Menu)
+	writeSzOrOrd(buf, dlg.Class)
+	writeSZ(buf, dlg.Title)
+
+	if dlg.Font != nil {
+		italic := uint8(0)
+		if dlg.Font.Italic {
+			italic = 1
+		}
+		binary.Write(buf, binary.LittleEndian, struct {
+			Size    uint16
+			Weight  uint16
+			Italic  uint8
+			Charset uint8
+		}{dlg.Font.Size, dlg.Font.Weight, italic, dlg.Font.Charset})
+		writeSZ(buf, dlg.Font.Name)
+	}
+
+	var errs errorList
+	for i, c := range dlg.Controls {
+		path := []string{"controls", strconv.Itoa(i)}
+		st, err := parseStyle(c.Style, windowStyles, controlStyles, classStyles)
+		if err != nil {
+			errs.add(atPath(err, append(path, "style")...))
+		}
+		ex, err := parseStyle(c.ExStyle, exStyles)
+		if err != nil {
+			errs.add(atPath(err, append(path, "ex_style")...))
+		}
+		if len(c.Data) > 0xFFFF {
+			errs.add(atPath(errors.New("creation data is too big"), append(path, "data")...))
+		}
+		if len(errs) > 0 {
+			continue
+		}
+
+		padDWORD(buf)
+		binary.Write(buf, binary.LittleEndian, struct {
+			HelpID  uint32
+			ExStyle uint32
+			Style   uint32
+			X, Y    int16
+			CX, CY  int16
+			ID      uint32
+		}{c.HelpID, ex, st, c.X, c.Y, c.Width, c.Height, c.ID})
+		writeControlClass(buf, c.Class)
+		writeSzOrOrd(buf, c.Text)
+		binary.Write(buf, binary.LittleEndian, uint16(len(c.Data)))
+		buf.Write(c.Data)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return buf.Bytes(), nil
+}
+
+// parseStyle parses a list of styles separated by commas or pipes.
+// Each style is either a name found in one of the tables, or a number.
+func parseStyle(s string, tables ...[]style) (uint32, error) {
+	var value uint32
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '|' }) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(name, 0, 32)
+		if err == nil {
+			value |= uint32(n)
+			continue
+		}
+		found := false
+		for _, t := range tables {
+			for _, st := range t {
+				if strings.EqualFold(st.name, name) {
+					value |= st.value
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("%s: %q", errUnknownStyle, name)
+		}
+	}
+	return value, nil
+}
+
+// styleString decodes a style using the given tables.
+// Bits that are not found in the tables are written as a number.
+func styleString(value uint32, tables ...[]style) string {
+	var names []string
+	for _, t := range tables {
+		for _, st := range t {
+			if st.value != 0 && value&st.value == st.value {
+				names = append(names, st.name)
+				value &^= st.value
+			}
+		}
+	}
+	if value != 0 {
+		names = append(names, fmt.Sprintf("0x%X", value))
+	}
+	return strings.Join(names, ",")
+}
+
+// writeSzOrOrd writes a string, or an ordinal if s is a number prefixed with '#'.
+// An empty string means there is no value.
+func writeSzOrOrd(buf *bytes.Buffer, s string) {
+	if len(s) > 1 && s[0] == '#' {
+		n, err := strconv.ParseUint(s[1:], 10, 16)
+		if err == nil {
+			binary.Write(buf, binary.LittleEndian, [2]uint16{0xFFFF, uint16(n)})
+			return
+		}
+	}
+	writeSZ(buf, s)
+}
+
+func writeControlClass(buf *bytes.Buffer, class string) {
+	for i, c := range controlClasses {
+		if strings.EqualFold(c, class) {
+			binary.Write(buf, binary.LittleEndian, [2]uint16{0xFFFF, uint16(firstClassAtom + i)})
+			return
+		}
+	}
+	writeSzOrOrd(buf, class)
+}
+
+func readSzOrOrd(data []byte, pos *int) (string, error) {
+	if *pos+2 > len(data) {
+		return "", errors.New(errInvalidDialog)
+	}
+	if binary.LittleEndian.Uint16(data[*pos:]) == 0xFFFF {
+		if *pos+4 > len(data) {
+			return "", errors.New(errInvalidDialog)
+		}
+		n := binary.LittleEndian.Uint16(data[*pos+2:])
+		*pos += 4
+		return "#" + strconv.Itoa(int(n)), nil
+	}
+	return readSZ(data, pos)
+}
+
+func readControlClass(data []byte, pos *int) (string, error) {
+	class, err := readSzOrOrd(data, pos)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(class, "#") {
+		n, _ := strconv.Atoi(class[1:])
+		if n >= firstClassAtom && n < firstClassAtom+len(controlClasses) {
+			return controlClasses[n-firstClassAtom], nil
+		}
+	}
+	return class, nil
+}
+
+// readDialog decodes a DIALOGEX or a DIALOG template.
+func readDialog(data []byte) (*dialog, error) {
+	if len(data) >= 4 && binary.LittleEndian.Uint16(data) == 1 && binary.LittleEndian.Uint16(data[2:]) == 0xFFFF {
+		return readDialogEx(data)
+	}
+	return readDialogStd(data)
+}
+
+func readDialogEx(data []byte) (*dialog, error) {
+	hdr := struct {
+		Version   uint16
+		Signature uint16
+		HelpID    uint32
+		ExStyle   uint32
+		Style     uint32
+		Count     uint16
+		X, Y      int16
+		CX, CY    int16
+	}{}
+	if binary.Read(bytes.NewReader(data), binary.LittleEndian, &hdr) != nil {
+		return nil, errors.New(errInvalidDialog)
+	}
+
+	dlg := &dialog{
+		X:       hdr.X,
+		Y:       hdr.Y,
+		Width:   hdr.CX,
+		Height:  hdr.CY,
+		Style:   styleString(hdr.Style, windowStyles, dialogStyles),
+		ExStyle: styleString(hdr.ExStyle, exStyles),
+		HelpID:  hdr.HelpID,
+	}
+
+	pos := 26
+	err := dlg.readHeaderStrings(data, &pos)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Style&dsSetFont != 0 {
+		if pos+6 > len(data) {
+			return nil, errors.New(errInvalidDialog)
+		}
+		dlg.Font = &dialogFont{
+			Size:    binary.LittleEndian.Uint16(data[pos:]),
+			Weight:  binary.LittleEndian.Uint16(data[pos+2:]),
+			Italic:  data[pos+4] != 0,
+			Charset: data[pos+5],
+		}
+		pos += 6
+		dlg.Font.Name, err = readSZ(data, &pos)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; i < int(hdr.Count); i++ {
+		pos = (pos + 3) &^ 3
+		item := struct {
+			HelpID  uint32
+			ExStyle uint32
+			Style   uint32
+			X, Y    int16
+			CX, CY  int16
+			ID      uint32
+		}{}
+		if pos > len(data) || binary.Read(bytes.NewReader(data[pos:]), binary.LittleEndian, &item) != nil {
+			return nil, errors.New(errInvalidDialog)
+		}
+		pos += 24
+		c := dialogControl{
+			ID:      item.ID,
+			X:       item.X,
+			Y:       item.Y,
+			Width:   item.CX,
+			Height:  item.CY,
+			Style:   styleString(item.Style, windowStyles, controlStyles),
+			ExStyle: styleString(item.ExStyle, exStyles),
+			HelpID:  item.HelpID,
+		}
+		err = c.readStrings(data, &pos, true)
+		if err != nil {
+			return nil, err
+		}
+		dlg.Controls = append(dlg.Controls, c)
+	}
+
+	return dlg, nil
+}
+
+// readDialogStd decodes an old DIALOG template, so it can be written back as a DIALOGEX template.
+//
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-dlgtemplate
+func readDialogStd(data []byte) (*dialog, error) {
+	hdr := struct {
+		Style   uint32
+		ExStyle uint32
+		Count   uint16
+		X, Y    int16
+		CX, CY  int16
+	}{}
+	if binary.Read(bytes.NewReader(data), binary.LittleEndian, &hdr) != nil {
+		return nil, errors.New(errInvalidDialog)
+	}
+
+	dlg := &dialog{
+		X:       hdr.X,
+		Y:       hdr.Y,
+		Width:   hdr.CX,
+		Height:  hdr.CY,
+		Style:   styleString(hdr.Style, windowStyles, dialogStyles),
+		ExStyle: styleString(hdr.ExStyle, exStyles),
+	}
+
+	pos := 18
+	err := dlg.readHeaderStrings(data, &pos)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Style&dsSetFont != 0 {
+		if pos+2 > len(data) {
+			return nil, errors.New(errInvalidDialog)
+		}
+		dlg.Font = &dialogFont{Size: binary.LittleEndian.Uint16(data[pos:])}
+		pos += 2
+		dlg.Font.Name, err = readSZ(data, &pos)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; i < int(hdr.Count); i++ {
+		pos = (pos + 3) &^ 3
+		item := struct {
+			Style   uint32
+			ExStyle uint32
+			X, Y    int16
+			CX, CY  int16
+			ID      uint16
+		}{}
+		if pos > len(data) || binary.Read(bytes.NewReader(data[pos:]), binary.LittleEndian, &item) != nil {
+			return nil, errors.New(errInvalidDialog)
+		}
+		pos += 18
+		c := dialogControl{
+			ID:      uint32(item.ID),
+			X:       item.X,
+			Y:       item.Y,
+			Width:   item.CX,
+			Height:  item.CY,
+			Style:   styleString(item.Style, windowStyles, controlStyles),
+			ExStyle: styleString(item.ExStyle, exStyles),
+		}
+		err = c.readStrings(data, &pos, false)
+		if err != nil {
+			return nil, err
+		}
+		dlg.Controls = append(dlg.Controls, c)
+	}
+
+	return dlg, nil
+}
+
+func (dlg *dialog) readHeaderStrings(data []byte, pos *int) error {
+	var err error
+	dlg.Menu, err = readSzOrOrd(data, pos)
+	if err != nil {
+		return err
+	}
+	dlg.Class, err = readSzOrOrd(data, pos)
+	if err != nil {
+		return err
+	}
+	dlg.Title, err = readSZ(data, pos)
+	return err
+}
+
+// readStrings reads the class, the text and the creation data of a control.
+//
+// The size of the creation data counts itself in DIALOG templates, but not in DIALOGEX templates.
+func (c *dialogControl) readStrings(data []byte, pos *int, ex bool) error {
+	var err error
+	c.Class, err = readControlClass(data, pos)
+	if err != nil {
+		return err
+	}
+	c.Text, err = readSzOrOrd(data, pos)
+	if err != nil {
+		return err
+	}
+	if *pos+2 > len(data) {
+		return errors.New(errInvalidDialog)
+	}
+	n := int(binary.LittleEndian.Uint16(data[*pos:]))
+	*pos += 2
+	if !ex && n > 0 {
+		if n < 2 {
+			return errors.New(errInvalidDialog)
+		}
+		n -= 2
+	}
+	if *pos+n > len(data) {
+		return errors.New(errInvalidDialog)
+	}
+	if n > 0 {
+		c.Data = append([]byte{}, data[*pos:*pos+n]...)
+	}
+	*pos += n
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_loadDialog(t *testing.T) {
+	var x interface{}
+	err := json.Unmarshal([]byte(`{
+		"x": 0, "y": 0, "width": 200, "height": 80,
+		"title": "About",
+		"style": "WS_POPUP | WS_CAPTION | WS_SYSMENU | DS_MODALFRAME",
+		"ex_style": "WS_EX_TOOLWINDOW",
+		"menu": "#3",
+		"font": {"name": "MS Shell Dlg", "size": 8, "weight": 400, "charset": 1},
+		"controls": [
+			{"class": "static", "text": "#1", "x": 8, "y": 8, "width": 20, "height": 20, "style": "WS_CHILD,WS_VISIBLE,SS_ICON"},
+			{"class": "Button", "text": "OK", "id": 1, "x": 140, "y": 60, "width": 50, "height": 14,
+			 "style": "WS_CHILD,WS_VISIBLE,WS_TABSTOP,BS_DEFPUSHBUTTON", "help_id": 5},
+			{"class": "SysLink", "text": "<a>Link</a>", "id": 1000, "x": -1, "y": 30, "width": 100, "height": 10,
+			 "style": "0x50000000", "data": "AQI="}
+		]
+	}`), &x)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := loadDialog("", x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 1 || data[2] != 0xFF || data[3] != 0xFF {
+		t.Errorf("bad DIALOGEX header: %v", data[:4])
+	}
+
+	dlg, err := readDialog(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &dialog{
+		Width:   200,
+		Height:  80,
+		Title:   "About",
+		Style:   "WS_POPUP,WS_CAPTION,WS_SYSMENU,DS_SETFONT,DS_MODALFRAME",
+		ExStyle: "WS_EX_TOOLWINDOW",
+		Menu:    "#3",
+		Font:    &dialogFont{Name: "MS Shell Dlg", Size: 8, Weight: 400, Charset: 1},
+		Controls: []dialogControl{
+			{Class: "STATIC", Text: "#1", X: 8, Y: 8, Width: 20, Height: 20, Style: "WS_CHILD,WS_VISIBLE,0x3"},
+			{Class: "BUTTON", Text: "OK", ID: 1, X: 140, Y: 60, Width: 50, Height: 14, Style: "WS_CHILD,WS_VISIBLE,WS_TABSTOP,0x1", HelpID: 5},
+			{Class: "SysLink", Text: "<a>Link</a>", ID: 1000, X: -1, Y: 30, Width: 100, Height: 10, Style: "WS_CHILD,WS_VISIBLE", Data: []byte{1, 2}},
+		},
+	}
+	if !reflect.DeepEqual(dlg, want) {
+		t.Errorf("readDialog() = %+v, want %+v", dlg, want)
+	}
+
+	again, err := dlg.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, data) {
+		t.Error("round trip should give the same template")
+	}
+}
+
+func Test_loadDialog_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		x    interface{}
+	}{
+		{"array", []interface{}{}},
+		{"style", map[string]interface{}{"style": "WS_BLINKING"}},
+		{"ex style", map[string]interface{}{"ex_style": "WS_POPUP"}},
+		{"font", map[string]interface{}{"style": "DS_SETFONT"}},
+		{"control style", map[string]interface{}{"controls": []interface{}{map[string]interface{}{"class": "EDIT", "style": "DS_CENTER"}}}},
+		{"control type", map[string]interface{}{"controls": "EDIT"}},
+		{"file", "missing.bin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadDialog("_testdata", tt.x); err == nil {
+				t.Error("loadDialog() should fail")
+			}
+		})
+	}
+}
+
+func Test_readDialog(t *testing.T) {
+	// Old DIALOG format, as compiled by rc.exe:
+	//   1 DIALOG 0, 0, 100, 50
+	//   STYLE DS_SETFONT | WS_POPUP
+	//   FONT 8, "A"
+	//   { PUSHBUTTON "B", 2, 10, 20, 30, 14 { 0xCDAB } }
+	// The size of the creation data counts itself.
+	data := []byte{
+		0x40, 0, 0, 0x80, 0, 0, 0, 0,
+		1, 0, 0, 0, 0, 0, 100, 0, 50, 0,
+		0, 0, 0, 0, 0, 0,
+		8, 0, 'A', 0, 0, 0,
+		0, 0,
+		0, 0, 0x01, 0x50, 0, 0, 0, 0,
+		10, 0, 20, 0, 30, 0, 14, 0, 2, 0,
+		0xFF, 0xFF, 0x80, 0,
+		'B', 0, 0, 0,
+		4, 0, 0xAB, 0xCD,
+	}
+
+	dlg, err := readDialog(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &dialog{
+		Width:  100,
+		Height: 50,
+		Style:  "WS_POPUP,DS_SETFONT",
+		Font:   &dialogFont{Name: "A", Size: 8},
+		Controls: []dialogControl{
+			{Class: "BUTTON", Text: "B", ID: 2, X: 10, Y: 20, Width: 30, Height: 14, Style: "WS_CHILD,WS_VISIBLE,WS_TABSTOP", Data: []byte{0xAB, 0xCD}},
+		},
+	}
+	if !reflect.DeepEqual(dlg, want) {
+		t.Errorf("readDialog() = %+v, want %+v", dlg, want)
+	}
+
+	for i := 0; i < len(data); i++ {
+		if _, err := readDialog(data[:i]); err == nil {
+			t.Errorf("readDialog() should fail with %d bytes", i)
+		}
+	}
+}
+
+func Test_readDialog_Compiled(t *testing.T) {
+	// dialogs.res was compiled from this script:
+	//   LANGUAGE 9, 1
+	//   1 DIALOG 10, 20, 100, 50
+	//   STYLE 0x80C00040
+	//   CAPTION "Old"
+	//   FONT 8, "MS Shell Dlg"
+	//   BEGIN
+	//     PUSHBUTTON "OK", 1, 10, 20, 30, 14
+	//     CONTROL "X", 3, "MyClass", 0x50000000, 1, 2, 3, 4
+	//   END
+	//   2 DIALOGEX 10, 20, 100, 50, 7
+	//   STYLE 0x80C00040
+	//   EXSTYLE 0x8
+	//   CAPTION "New"
+	//   FONT 9, "Segoe UI", 700, 1, 1
+	//   BEGIN
+	//     EDITTEXT 4, 10, 20, 30, 14, 0x50800080, 0x200, 5
+	//     CONTROL "X", 3, "MyClass", 0x50000000, 1, 2, 3, 4
+	//   END
+	b, err := ioutil.ReadFile(filepath.Join("_testdata", "dialogs.res"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := &winres.ResourceSet{}
+	err = readRES(rs, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   winres.ID
+		want *dialog
+	}{
+		{1, &dialog{
+			X: 10, Y: 20, Width: 100, Height: 50,
+			Title: "Old",
+			Style: "WS_POPUP,WS_CAPTION,DS_SETFONT",
+			Font:  &dialogFont{Name: "MS Shell Dlg", Size: 8},
+			Controls: []dialogControl{
+				{Class: "BUTTON", Text: "OK", ID: 1, X: 10, Y: 20, Width: 30, Height: 14, Style: "WS_CHILD,WS_VISIBLE,WS_TABSTOP"},
+				{Class: "MyClass", Text: "X", ID: 3, X: 1, Y: 2, Width: 3, Height: 4, Style: "WS_CHILD,WS_VISIBLE"},
+			},
+		}},
+		{2, &dialog{
+			X: 10, Y: 20, Width: 100, Height: 50,
+			Title:   "New",
+			Style:   "WS_POPUP,WS_CAPTION,DS_SETFONT",
+			ExStyle: "WS_EX_TOPMOST",
+			HelpID:  7,
+			Font:    &dialogFont{Name: "Segoe UI", Size: 9, Weight: 700, Italic: true, Charset: 1},
+			Controls: []dialogControl{
+				{Class: "EDIT", ID: 4, X: 10, Y: 20, Width: 30, Height: 14, Style: "WS_CHILD,WS_VISIBLE,WS_BORDER,WS_TABSTOP,0x80", ExStyle: "WS_EX_CLIENTEDGE", HelpID: 5},
+				{Class: "MyClass", Text: "X", ID: 3, X: 1, Y: 2, Width: 3, Height: 4, Style: "WS_CHILD,WS_VISIBLE"},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		data := rs.Get(winres.RT_DIALOG, tt.id, 0x409)
+		dlg, err := readDialog(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(dlg, tt.want) {
+			t.Errorf("readDialog(%d) = %+v, want %+v", tt.id, dlg, tt.want)
+		}
+	}
+
+	// The DIALOGEX template is written back as compiled
+	dlg, _ := readDialog(rs.Get(winres.RT_DIALOG, winres.ID(2), 0x409))
+	again, err := dlg.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, rs.Get(winres.RT_DIALOG, winres.ID(2), 0x409)) {
+		t.Errorf("dlg.bytes() =\n% X\nwant\n% X", again, rs.Get(winres.RT_DIALOG, winres.ID(2), 0x409))
+	}
+}
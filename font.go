@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"unicode/utf16"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errFontID      = "fonts must have a numeric ID"
+	errUnknownFont = "unsupported font file (expected .ttf, .otf, .fnt or .fon)"
+	errInvalidFON  = "invalid .fon file"
+)
+
+// Size of the part of a .fnt header that is copied to a font directory entry
+const fontDirHeaderSize = 113
+
+const fntVersion3 = 0x0300
+
+// Values for dfPitchAndFamily (the meaning of TMPF_FIXED_PITCH is inverted)
+const (
+	tmpfVariablePitch = 0x01
+	tmpfVector        = 0x02
+	tmpfTrueType      = 0x04
+)
+
+const symbolCharset = 2
+
+// fontDirHeader is the beginning of a .fnt header, which is also the beginning of a FONTDIRENTRY.
+//
+// https://docs.microsoft.com/en-us/windows/win32/menurc/fontdirentry
+type fontDirHeader struct {
+	Version         uint16
+	Size            uint32
+	Copyright       [60]byte
+	Type            uint16
+	Points          uint16
+	VertRes         uint16
+	HorizRes        uint16
+	Ascent          uint16
+	InternalLeading uint16
+	ExternalLeading uint16
+	Italic          uint8
+	Underline       uint8
+	StrikeOut       uint8
+	Weight          uint16
+	CharSet         uint8
+	PixWidth        uint16
+	PixHeight       uint16
+	PitchAndFamily  uint8
+	AvgWidth        uint16
+	MaxWidth        uint16
+	FirstChar       uint8
+	LastChar        uint8
+	DefaultChar     uint8
+	BreakChar       uint8
+	WidthBytes      uint16
+	Device          uint32
+	Face            uint32
+	Reserved        uint32
+}
+
+// importFont adds a font file as an RT_FONT resource.
+// A .fon file may contain several fonts, which get consecutive IDs.
+func importFont(rs *winres.ResourceSet, name string, resID winres.Identifier, langID uint16) error {
+	id, ok := resID.(winres.ID)
+	if !ok {
+		return errors.New(errFontID)
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	fonts := [][]byte{data}
+	if isFON(data) {
+		fonts, err = readFON(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, f := range fonts {
+		_, _, err = fontDirEntry(f)
+		if err != nil {
+			return err
+		}
+		err = rs.Set(winres.RT_FONT, id+winres.ID(i), langID, f)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkFontIDs reports .fon files whose fonts would replace other fonts of the definition.
+//
+// A .fon file with N fonts takes N consecutive IDs, so the IDs that follow its own must not be declared.
+func checkFontIDs(dir string, res jsonDef) error {
+	type font struct {
+		tid, rid, lid string
+		id            winres.ID
+		langID        uint16
+		data          interface{}
+	}
+	var (
+		fonts    []font
+		declared = make(map[uint16]map[winres.ID]string)
+	)
+	for _, tid := range sortedTypes(res) {
+		for _, r := range sortedRes(res[tid]) {
+			for _, l := range sortedLang(r.langs) {
+				typeID, resID, langID, err := idsFromStrings(tid, r.id, l.id)
+				id, ok := resID.(winres.ID)
+				if err != nil || typeID != winres.RT_FONT || !ok {
+					continue
+				}
+				if declared[langID] == nil {
+					declared[langID] = make(map[winres.ID]string)
+				}
+				declared[langID][id] = r.id
+				fonts = append(fonts, font{tid, r.id, l.id, id, langID, l.data})
+			}
+		}
+	}
+
+	var errs errorList
+	for _, f := range fonts {
+		name, ok := f.data.(string)
+		if !ok {
+			continue
+		}
+		// Invalid files are reported when they are imported
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || !isFON(data) {
+			continue
+		}
+		faces, err := readFON(data)
+		if err != nil {
+			continue
+		}
+		for i := 1; i < len(faces); i++ {
+			id := f.id + winres.ID(i)
+			if other, ok := declared[f.langID][id]; ok {
+				errs.add(atPath(fmt.Errorf("font #%d of %q would replace %q", id, name, other), f.tid, f.rid, f.lid))
+			}
+		}
+	}
+	return errs.err()
+}
+
+// setFontDir replaces the RT_FONTDIR resource with a directory of all RT_FONT resources.
+//
+// https://docs.microsoft.com/en-us/windows/win32/menurc/fontgroupheader
+func setFontDir(rs *winres.ResourceSet) {
+	type dirEntry struct {
+		id   winres.ID
+		lang uint16
+		data []byte
+	}
+	var (
+		entries []dirEntry
+		seen    = make(map[winres.ID]bool)
+	)
+	rs.WalkType(winres.RT_FONT, func(resID winres.Identifier, langID uint16, data []byte) bool {
+		id, ok := resID.(winres.ID)
+		if !ok || seen[id] {
+			return true
+		}
+		_, entry, err := fontDirEntry(data)
+		if err != nil {
+			log.Printf("font #%d is not in the font directory: %v", id, err)
+			return true
+		}
+		seen[id] = true
+		entries = append(entries, dirEntry{id, langID, entry})
+		return true
+	})
+
+	var old []struct {
+		resID  winres.Identifier
+		langID uint16
+	}
+	rs.WalkType(winres.RT_FONTDIR, func(resID winres.Identifier, langID uint16, _ []byte) bool {
+		old = append(old, struct {
+			resID  winres.Identifier
+			langID uint16
+		}{resID, langID})
+		return true
+	})
+	for _, o := range old {
+		rs.Set(winres.RT_FONTDIR, o.resID, o.langID, nil)
+	}
+
+	if len(entries) == 0 {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(buf, binary.LittleEndian, uint16(e.id))
+		buf.Write(e.data)
+	}
+	rs.Set(winres.RT_FONTDIR, winres.Name("FONTDIR"), entries[0].lang, buf.Bytes())
+}
+
+// fontDirEntry returns the extension of a font file and its font directory entry.
+func fontDirEntry(data []byte) (string, []byte, error) {
+	if ext := sfntExt(data); ext != "" {
+		entry, err := sfntDirEntry(data)
+		return ext, entry, err
+	}
+	if isFNT(data) {
+		return "fnt", fntDirEntry(data), nil
+	}
+	return "", nil, errors.New(errUnknownFont)
+}
+
+func sfntExt(data []byte) string {
+	if len(data) < 12 {
+		return ""
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true":
+		return "ttf"
+	case "OTTO":
+		return "otf"
+	}
+	return ""
+}
+
+func isFNT(data []byte) bool {
+	if len(data) < fontDirHeaderSize+4 {
+		return false
+	}
+	v := binary.LittleEndian.Uint16(data)
+	return (v == 0x0200 || v == fntVersion3) && int(binary.LittleEndian.Uint32(data[2:])) == len(data)
+}
+
+// fntDirEntry copies the header of a .fnt file, followed by its device name and face name.
+func fntDirEntry(data []byte) []byte {
+	buf := &bytes.Buffer{}
+	buf.Write(data[:fontDirHeaderSize])
+	buf.WriteString(cString(data, binary.LittleEndian.Uint32(data[101:])))
+	buf.WriteByte(0)
+	buf.WriteString(cString(data, binary.LittleEndian.Uint32(data[105:])))
+	buf.WriteByte(0)
+	return buf.Bytes()
+}
+
+func cString(data []byte, offset uint32) string {
+	if offset == 0 || int(offset) >= len(data) {
+		return ""
+	}
+	s := data[offset:]
+	if n := bytes.IndexByte(s, 0); n >= 0 {
+		s = s[:n]
+	}
+	return string(s)
+}
+
+// sfntDirEntry makes a font directory entry from the tables of a TrueType or OpenType font.
+// Metrics are in font units.
+func sfntDirEntry(data []byte) ([]byte, error) {
+	tables, err := sfntTables(data)
+	if err != nil {
+		return nil, err
+	}
+
+	h := fontDirHeader{
+		Version:        fntVersion3,
+		Size:           uint32(len(data)),
+		VertRes:        96,
+		HorizRes:       96,
+		Weight:         400,
+		PitchAndFamily: tmpfVariablePitch | tmpfVector | tmpfTrueType,
+		FirstChar:      0x20,
+		LastChar:       0xFF,
+	}
+
+	head := tables["head"]
+	if len(head) < 54 {
+		return nil, errors.New(errUnknownFont)
+	}
+	macStyle := binary.BigEndian.Uint16(head[44:])
+	if macStyle&1 != 0 {
+		h.Weight = 700
+	}
+	if macStyle&2 != 0 {
+		h.Italic = 1
+	}
+
+	if os2 := tables["OS/2"]; len(os2) >= 78 {
+		h.AvgWidth = binary.BigEndian.Uint16(os2[2:])
+		h.Weight = binary.BigEndian.Uint16(os2[4:])
+		sel := binary.BigEndian.Uint16(os2[62:])
+		h.Italic = uint8(sel & 1)
+		h.Underline = uint8(sel >> 1 & 1)
+		h.StrikeOut = uint8(sel >> 4 & 1)
+		h.FirstChar = uint8(minInt(int(binary.BigEndian.Uint16(os2[64:])), 0xFF))
+		h.LastChar = uint8(minInt(int(binary.BigEndian.Uint16(os2[66:])), 0xFF))
+		h.Ascent = binary.BigEndian.Uint16(os2[74:])
+		h.PixHeight = h.Ascent + binary.BigEndian.Uint16(os2[76:])
+	}
+	if hhea := tables["hhea"]; len(hhea) >= 12 {
+		h.ExternalLeading = binary.BigEndian.Uint16(hhea[8:])
+		h.MaxWidth = binary.BigEndian.Uint16(hhea[10:])
+	}
+	if post := tables["post"]; len(post) >= 16 && binary.BigEndian.Uint32(post[12:]) != 0 {
+		h.PitchAndFamily &^= tmpfVariablePitch
+		h.PixWidth = h.AvgWidth
+	}
+	if unitsPerEm := binary.BigEndian.Uint16(head[18:]); h.PixHeight > unitsPerEm {
+		h.InternalLeading = h.PixHeight - unitsPerEm
+	}
+	if h.FirstChar <= ' ' {
+		h.BreakChar = ' ' - h.FirstChar
+	}
+	if isSymbolFont(tables["cmap"]) {
+		h.CharSet = symbolCharset
+	}
+
+	names := tables["name"]
+	copy(h.Copyright[:], latin1(sfntName(names, 0)))
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, &h)
+	buf.WriteByte(0)
+	buf.Write(latin1(sfntName(names, 1)))
+	buf.WriteByte(0)
+
+	return buf.Bytes(), nil
+}
+
+// sfntTables returns the tables of a TrueType or OpenType font, by tag.
+//
+// https://docs.microsoft.com/en-us/typography/opentype/spec/otff#organization-of-an-opentype-font
+func sfntTables(data []byte) (map[string][]byte, error) {
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	if 12+n*16 > len(data) {
+		return nil, errors.New(errUnknownFont)
+	}
+	tables := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		rec := data[12+i*16:]
+		offset := uint64(binary.BigEndian.Uint32(rec[8:]))
+		length := uint64(binary.BigEndian.Uint32(rec[12:]))
+		if offset+length > uint64(len(data)) {
+			return nil, errors.New(errUnknownFont)
+		}
+		tables[string(rec[:4])] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// sfntName looks for a string in the name table, preferably in English.
+//
+// https://docs.microsoft.com/en-us/typography/opentype/spec/name
+func sfntName(name []byte, nameID uint16) string {
+	if len(name) < 6 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(name[2:]))
+	storage := int(binary.BigEndian.Uint16(name[4:]))
+
+	var found string
+	for i := 0; i < count && 6+i*12+12 <= len(name); i++ {
+		rec := name[6+i*12:]
+		platform := binary.BigEndian.Uint16(rec)
+		lang := binary.BigEndian.Uint16(rec[4:])
+		length := int(binary.BigEndian.Uint16(rec[8:]))
+		offset := storage + int(binary.BigEndian.Uint16(rec[10:]))
+		if binary.BigEndian.Uint16(rec[6:]) != nameID || offset+length > len(name) {
+			continue
+		}
+		s := name[offset : offset+length]
+
+		switch platform {
+		case 3:
+			u := make([]uint16, len(s)/2)
+			for j := range u {
+				u[j] = binary.BigEndian.Uint16(s[j*2:])
+			}
+			if lang == 0x409 {
+				return string(utf16.Decode(u))
+			}
+			found = string(utf16.Decode(u))
+		case 1:
+			if found == "" {
+				found = string(s)
+			}
+		}
+	}
+	return found
+}
+
+// isSymbolFont tells if a font has a (3, 0) cmap subtable, which means it uses the symbol character set.
+func isSymbolFont(cmap []byte) bool {
+	if len(cmap) < 4 {
+		return false
+	}
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n && 4+i*8+8 <= len(cmap); i++ {
+		if binary.BigEndian.Uint16(cmap[4+i*8:]) == 3 && binary.BigEndian.Uint16(cmap[6+i*8:]) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// latin1 converts a string for a font directory, which has no defined code page.
+func latin1(s string) []byte {
+	var b []byte
+	for _, r := range s {
+		if r > 0xFF {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func isFON(data []byte) bool {
+	return len(data) >= 0x40 && string(data[:2]) == "MZ"
+}
+
+// readFON returns the .fnt fonts contained in a .fon file, which is a 16-bit NE executable.
+func readFON(data []byte) ([][]byte, error) {
+	ne := int(binary.LittleEndian.Uint32(data[0x3C:]))
+	if ne+0x28 > len(data) || string(data[ne:ne+2]) != "NE" {
+		return nil, errors.New(errInvalidFON)
+	}
+
+	const rtFont = 0x8000 | uint16(winres.RT_FONT)
+
+	pos := ne + int(binary.LittleEndian.Uint16(data[ne+0x24:]))
+	if pos+2 > len(data) {
+		return nil, errors.New(errInvalidFON)
+	}
+	shift := binary.LittleEndian.Uint16(data[pos:])
+	pos += 2
+
+	var fonts [][]byte
+	for {
+		if pos+2 > len(data) || shift > 16 {
+			return nil, errors.New(errInvalidFON)
+		}
+		typeID := binary.LittleEndian.Uint16(data[pos:])
+		if typeID == 0 {
+			break
+		}
+		if pos+8 > len(data) {
+			return nil, errors.New(errInvalidFON)
+		}
+		count := int(binary.LittleEndian.Uint16(data[pos+2:]))
+		pos += 8
+		for i := 0; i < count; i++ {
+			if pos+12 > len(data) {
+				return nil, errors.New(errInvalidFON)
+			}
+			offset := int(binary.LittleEndian.Uint16(data[pos:])) << shift
+			length := int(binary.LittleEndian.Uint16(data[pos+2:])) << shift
+			pos += 12
+			if typeID != rtFont {
+				continue
+			}
+			if offset+length > len(data) {
+				return nil, errors.New(errInvalidFON)
+			}
+			fnt := data[offset : offset+length]
+			// Resources are padded to the alignment unit
+			if len(fnt) > 6 {
+				if size := int(binary.LittleEndian.Uint32(fnt[2:])); size <= len(fnt) {
+					fnt = fnt[:size]
+				}
+			}
+			fonts = append(fonts, append([]byte{}, fnt...))
+		}
+	}
+
+	if len(fonts) == 0 {
+		return nil, errors.New(errInvalidFON)
+	}
+	return fonts, nil
+}
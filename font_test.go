@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_sfntDirEntry(t *testing.T) {
+	entry, err := sfntDirEntry(makeTestSFNT(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := fontDirHeader{}
+	binary.Read(bytes.NewReader(entry), binary.LittleEndian, &h)
+	if h.Version != fntVersion3 || h.Size != uint32(len(makeTestSFNT(false))) {
+		t.Errorf("wrong version or size %+v", h)
+	}
+	if string(bytes.TrimRight(h.Copyright[:], "\x00")) != "(c) Test" {
+		t.Errorf("wrong copyright %q", h.Copyright)
+	}
+	if h.Weight != 700 || h.Italic != 1 || h.Underline != 0 || h.StrikeOut != 0 {
+		t.Errorf("wrong style %+v", h)
+	}
+	if h.Ascent != 900 || h.PixHeight != 1100 || h.InternalLeading != 100 || h.ExternalLeading != 50 {
+		t.Errorf("wrong metrics %+v", h)
+	}
+	if h.AvgWidth != 500 || h.MaxWidth != 1200 || h.PixWidth != 0 {
+		t.Errorf("wrong widths %+v", h)
+	}
+	if h.PitchAndFamily != tmpfVariablePitch|tmpfVector|tmpfTrueType || h.CharSet != 0 {
+		t.Errorf("wrong pitch or charset %+v", h)
+	}
+	if h.FirstChar != 0x20 || h.LastChar != 0xFF || h.BreakChar != 0 {
+		t.Errorf("wrong characters %+v", h)
+	}
+	if names := entry[fontDirHeaderSize:]; string(names) != "\x00T\xE9st\x00" {
+		t.Errorf("wrong names %q", names)
+	}
+
+	entry, err = sfntDirEntry(makeTestSFNT(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.Read(bytes.NewReader(entry), binary.LittleEndian, &h)
+	if h.PitchAndFamily != tmpfVector|tmpfTrueType || h.PixWidth != 500 || h.CharSet != symbolCharset {
+		t.Errorf("wrong pitch or charset %+v", h)
+	}
+}
+
+func Test_fntDirEntry(t *testing.T) {
+	fnt := makeTestFNT("Face")
+	ext, entry, err := fontDirEntry(fnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext != "fnt" {
+		t.Errorf("wrong extension %q", ext)
+	}
+	want := append(append([]byte{}, fnt[:fontDirHeaderSize]...), "\x00Face\x00"...)
+	if !bytes.Equal(entry, want) {
+		t.Errorf("fntDirEntry() =\n%v\nwant\n%v", entry, want)
+	}
+}
+
+func Test_readFON(t *testing.T) {
+	fonts, err := readFON(makeTestFON(makeTestFNT("A"), makeTestFNT("BB")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fonts) != 2 || !bytes.Equal(fonts[0], makeTestFNT("A")) || !bytes.Equal(fonts[1], makeTestFNT("BB")) {
+		t.Errorf("readFON() = %v", fonts)
+	}
+
+	_, err = readFON(makeTestFON()[:0x60])
+	if err == nil || err.Error() != errInvalidFON {
+		t.Errorf("readFON() error = %v, want %q", err, errInvalidFON)
+	}
+}
+
+func Test_importDef_Fonts(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	files := map[string][]byte{
+		"font.ttf":  makeTestSFNT(false),
+		"fonts.fon": makeTestFON(makeTestFNT("A"), makeTestFNT("B")),
+		"other.txt": []byte("not a font"),
+	}
+	for name, data := range files {
+		err := ioutil.WriteFile(filepath.Join(tmpDir, name), data, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rs := &winres.ResourceSet{}
+	err := importDef(rs, tmpDir, jsonDef{"RT_FONT": {
+		"#1": {"0409": "font.ttf"},
+		"#5": {"0409": "fonts.fon"},
+		"#6": {"040C": "font.ttf"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []winres.ID{1, 5, 6} {
+		if rs.Get(winres.RT_FONT, id, 0x409) == nil {
+			t.Errorf("missing font #%d", id)
+		}
+	}
+
+	dir := rs.Get(winres.RT_FONTDIR, winres.Name("FONTDIR"), 0x409)
+	if len(dir) < 2 || binary.LittleEndian.Uint16(dir) != 3 {
+		t.Fatalf("wrong font directory %v", dir)
+	}
+	entry, _ := sfntDirEntry(makeTestSFNT(false))
+	if binary.LittleEndian.Uint16(dir[2:]) != 1 || !bytes.Equal(dir[4:4+len(entry)], entry) {
+		t.Error("wrong first font directory entry")
+	}
+	pos := 4 + len(entry)
+	if binary.LittleEndian.Uint16(dir[pos:]) != 5 {
+		t.Error("wrong second font directory entry")
+	}
+
+	errTests := []struct {
+		def jsonDef
+		err string
+	}{
+		{jsonDef{"RT_FONT": {"NAME": {"0409": "font.ttf"}}}, "[RT_FONT][NAME][0409] " + errFontID},
+		{jsonDef{"RT_FONT": {"#1": {"0409": "other.txt"}}}, "[RT_FONT][#1][0409] " + errUnknownFont},
+		{jsonDef{"RT_FONT": {"#1": {"0409": 42.0}}}, "[RT_FONT][#1][0409] " + errInvalidSet},
+		{jsonDef{"RT_FONT": {"#1": {"0409": "fonts.fon"}, "#2": {"0409": "font.ttf"}}}, `[RT_FONT][#1][0409] font #2 of "fonts.fon" would replace "#2"`},
+		{jsonDef{"RT_FONT": {"#2": {"0409": "fonts.fon"}, "#3": {"0409": "fonts.fon"}}}, `[RT_FONT][#2][0409] font #3 of "fonts.fon" would replace "#3"`},
+	}
+	for _, tt := range errTests {
+		err := importDef(&winres.ResourceSet{}, tmpDir, tt.def)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("importDef() error = %v, want %q", err, tt.err)
+		}
+	}
+
+	rs = &winres.ResourceSet{}
+	err = importDef(rs, tmpDir, jsonDef{
+		"RT_FONT":    {"#1": {"0409": "font.ttf"}},
+		"RT_FONTDIR": {"CUSTOM": {"0409": "other.txt"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Get(winres.RT_FONTDIR, winres.Name("FONTDIR"), 0x409) != nil || rs.Get(winres.RT_FONTDIR, winres.Name("CUSTOM"), 0x409) == nil {
+		t.Error("an explicit font directory should be kept")
+	}
+}
+
+func Test_exportedName_Font(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{makeTestSFNT(false), "RT_FONT_#1_0409.ttf"},
+		{append([]byte("OTTO"), makeTestSFNT(false)[4:]...), "RT_FONT_#1_0409.otf"},
+		{makeTestFNT("Face"), "RT_FONT_#1_0409.fnt"},
+		{[]byte("something else"), "RT_FONT_#1_0409.bin"},
+	}
+	for _, tt := range tests {
+		if got := exportedName(false, tt.data, winres.RT_FONT, winres.ID(1), 0x409); got != tt.want {
+			t.Errorf("exportedName() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// makeTestSFNT makes a font that only has the tables used in a font directory entry.
+func makeTestSFNT(mono bool) []byte {
+	be := binary.BigEndian
+	tables := map[string][]byte{}
+
+	head := make([]byte, 54)
+	be.PutUint16(head[18:], 1000)
+	be.PutUint16(head[44:], 3)
+	tables["head"] = head
+
+	os2 := make([]byte, 78)
+	be.PutUint16(os2[2:], 500)
+	be.PutUint16(os2[4:], 700)
+	be.PutUint16(os2[62:], 1)
+	be.PutUint16(os2[64:], 0x20)
+	be.PutUint16(os2[66:], 0xFFFD)
+	be.PutUint16(os2[74:], 900)
+	be.PutUint16(os2[76:], 200)
+	tables["OS/2"] = os2
+
+	hhea := make([]byte, 36)
+	be.PutUint16(hhea[8:], 50)
+	be.PutUint16(hhea[10:], 1200)
+	tables["hhea"] = hhea
+
+	post := make([]byte, 32)
+	if mono {
+		be.PutUint32(post[12:], 1)
+	}
+	tables["post"] = post
+
+	cmap := make([]byte, 12)
+	be.PutUint16(cmap[2:], 1)
+	be.PutUint16(cmap[4:], 3)
+	if !mono {
+		be.PutUint16(cmap[6:], 1)
+	}
+	tables["cmap"] = cmap
+
+	name := &bytes.Buffer{}
+	strs := []struct {
+		platform, lang, id uint16
+		s                  []byte
+	}{
+		{1, 0, 1, []byte("Mac name")},
+		{3, 0x409, 0, utf16BE("(c) Test")},
+		{3, 0x40C, 1, utf16BE("Français")},
+		{3, 0x409, 1, utf16BE("Tést")},
+	}
+	binary.Write(name, be, [3]uint16{0, uint16(len(strs)), uint16(6 + 12*len(strs))})
+	storage := &bytes.Buffer{}
+	for _, s := range strs {
+		binary.Write(name, be, [6]uint16{s.platform, 1, s.lang, s.id, uint16(len(s.s)), uint16(storage.Len())})
+		storage.Write(s.s)
+	}
+	name.Write(storage.Bytes())
+	tables["name"] = name.Bytes()
+
+	tags := []string{"OS/2", "cmap", "head", "hhea", "name", "post"}
+	buf := &bytes.Buffer{}
+	binary.Write(buf, be, uint32(0x00010000))
+	binary.Write(buf, be, [4]uint16{uint16(len(tags)), 0, 0, 0})
+	offset := 12 + 16*len(tags)
+	for _, tag := range tags {
+		buf.WriteString(tag)
+		binary.Write(buf, be, [3]uint32{0, uint32(offset), uint32(len(tables[tag]))})
+		offset += len(tables[tag])
+	}
+	for _, tag := range tags {
+		buf.Write(tables[tag])
+	}
+	return buf.Bytes()
+}
+
+func utf16BE(s string) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, utf16.Encode([]rune(s)))
+	return buf.Bytes()
+}
+
+// makeTestFNT makes a .fnt header followed by a face name.
+func makeTestFNT(face string) []byte {
+	fnt := make([]byte, 118, 118+len(face)+1)
+	fnt = append(fnt, face...)
+	fnt = append(fnt, 0)
+	binary.LittleEndian.PutUint16(fnt, fntVersion3)
+	binary.LittleEndian.PutUint32(fnt[2:], uint32(len(fnt)))
+	copy(fnt[6:], "Copyright")
+	binary.LittleEndian.PutUint32(fnt[105:], 118)
+	return fnt
+}
+
+// makeTestFON makes an NE executable with .fnt files as RT_FONT resources.
+func makeTestFON(fonts ...[]byte) []byte {
+	const shift = 4
+	fon := make([]byte, 0x100)
+	copy(fon, "MZ")
+	binary.LittleEndian.PutUint32(fon[0x3C:], 0x40)
+	copy(fon[0x40:], "NE")
+	binary.LittleEndian.PutUint16(fon[0x40+0x24:], 0x40)
+
+	rt := &bytes.Buffer{}
+	binary.Write(rt, binary.LittleEndian, [5]uint16{shift, 0x8008, uint16(len(fonts)), 0, 0})
+	offset := len(fon)
+	for i, f := range fonts {
+		size := (len(f) + 1<<shift - 1) >> shift
+		binary.Write(rt, binary.LittleEndian, [6]uint16{uint16(offset >> shift), uint16(size), 0x30, 0x8001 + uint16(i), 0, 0})
+		offset += size << shift
+	}
+	binary.Write(rt, binary.LittleEndian, uint16(0))
+	copy(fon[0x80:], rt.Bytes())
+
+	for _, f := range fonts {
+		fon = append(fon, f...)
+		for len(fon)%(1<<shift) != 0 {
+			fon = append(fon, 0)
+		}
+	}
+	return fon
+}
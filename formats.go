@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const errYAMLKey = "yaml keys must be scalars"
+
+// defFormat tells the format of a definition file from its extension.
+func defFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".toml":
+		return formatTOML
+	case ".rc":
+		return formatRC
+	}
+	return formatJSON
+}
+
+// toJSON converts a yaml or toml definition to json, so it can be read like winres.json.
+// Comments and trailing commas are removed from json.
+func toJSON(b []byte, format string) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		return stripJSONC(b)
+	case formatYAML:
+		return yamlToJSON(b)
+	case formatTOML:
+		return tomlToJSON(b)
+	}
+	return b, nil
+}
+
+// fromJSON converts a json definition to yaml or toml.
+func fromJSON(b []byte, format string) ([]byte, error) {
+	switch format {
+	case formatYAML:
+		return jsonToYAML(b)
+	case formatTOML:
+		return jsonToTOML(b)
+	}
+	return b, nil
+}
+
+// yamlToJSON converts yaml to json.
+//
+// Mapping keys are always strings, as they are written.
+// This matters for language IDs such as 0409, that yaml would read as numbers.
+func yamlToJSON(b []byte) ([]byte, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return []byte("{}"), nil
+	}
+
+	v, err := yamlValue(doc.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func yamlValue(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return yamlValue(n.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if k.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: %s", k.Line, errYAMLKey)
+			}
+			v, err := yamlValue(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[k.Value] = v
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		s := make([]interface{}, len(n.Content))
+		for i := range n.Content {
+			v, err := yamlValue(n.Content[i])
+			if err != nil {
+				return nil, err
+			}
+			s[i] = v
+		}
+		return s, nil
+	}
+
+	var v interface{}
+	err := n.Decode(&v)
+	return v, err
+}
+
+// jsonToYAML converts json to yaml, keeping the order of keys.
+func jsonToYAML(b []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	n, err := jsonToYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	err = enc.Encode(n)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Close()
+	return buf.Bytes(), err
+}
+
+func jsonToYAMLNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if tok == '{' {
+			n = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for dec.More() {
+			if n.Kind == yaml.MappingNode {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k.(string)})
+			}
+			v, err := jsonToYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, v)
+		}
+		_, err = dec.Token()
+		return n, err
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tok}, nil
+	case json.Number:
+		tag := "!!int"
+		if _, err := tok.Int64(); err != nil {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: tok.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(tok)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, errors.New("unexpected json token")
+}
+
+// tomlToJSON converts toml to json.
+func tomlToJSON(b []byte) ([]byte, error) {
+	var v map[string]interface{}
+	err := toml.Unmarshal(b, &v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// jsonToTOML converts json to toml.
+// Null values are dropped, because toml has no such thing.
+func jsonToTOML(b []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	enc := toml.NewEncoder(buf)
+	enc.Indent = ""
+	err = enc.Encode(tomlValue(v))
+	return buf.Bytes(), err
+}
+
+func tomlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, x := range v {
+			if x == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = tomlValue(x)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = tomlValue(v[i])
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tc-hib/winres"
+	"github.com/tc-hib/winres/version"
+)
+
+func Test_defFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"winres.json", formatJSON},
+		{"winres.YAML", formatYAML},
+		{"dir/winres.yml", formatYAML},
+		{"winres.toml", formatTOML},
+		{"winres.rc", formatRC},
+		{"winres", formatJSON},
+	}
+	for _, tt := range tests {
+		if got := defFormat(tt.name); got != tt.want {
+			t.Errorf("defFormat(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_toJSON(t *testing.T) {
+	want := jsonDef{
+		"RT_RCDATA": {
+			"#1":   {"0409": "a.bin"},
+			"DATA": {"040C": map[string]interface{}{"text": "0409"}},
+		},
+	}
+	tests := []struct {
+		format string
+		def    string
+	}{
+		{formatYAML, "RT_RCDATA:\n  \"#1\":\n    0409: a.bin\n  DATA:\n    040C: {text: \"0409\"}\n"},
+		{formatTOML, "[RT_RCDATA.\"#1\"]\n0409 = \"a.bin\"\n[RT_RCDATA.DATA.040C]\ntext = \"0409\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			b, err := toJSON([]byte(tt.def), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := jsonDef{}
+			err = json.Unmarshal(b, &res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res, want) {
+				t.Errorf("toJSON() = %s", b)
+			}
+		})
+	}
+
+	_, err := toJSON([]byte("RT_RCDATA:\n  [a, b]: c\n"), formatYAML)
+	if err == nil || err.Error() != "line 2: "+errYAMLKey {
+		t.Errorf("toJSON() error = %v", err)
+	}
+}
+
+func Test_fromJSON_InitJSON(t *testing.T) {
+	var want interface{}
+	b, _ := stripJSONC([]byte(initJSON))
+	json.Unmarshal(b, &want)
+
+	for _, format := range []string{formatYAML, formatTOML} {
+		b, err := fromJSON(b, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err = toJSON(b, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got interface{}
+		json.Unmarshal(b, &got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %s", format, b)
+		}
+	}
+
+	b, _ = fromJSON(b, formatYAML)
+	if !strings.HasPrefix(string(b), "$schema: winres.schema.json\nRT_GROUP_ICON:\n  APP:\n    \"0000\":\n") {
+		t.Errorf("keys should keep their order and language IDs should be quoted:\n%s", b)
+	}
+}
+
+func Test_exportResources_Formats(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	rs := &winres.ResourceSet{}
+	rs.Set(winres.RT_RCDATA, winres.Name("DATA"), 0x409, []byte("data"))
+	rs.Set(winres.RT_RCDATA, winres.ID(1), 0x40C, []byte{0, 1, 2})
+	vi := version.Info{}
+	vi.Set(0x409, version.ProductName, "Product")
+	rs.SetVersionInfo(vi)
+
+	for _, format := range []string{formatYAML, formatTOML} {
+		t.Run(format, func(t *testing.T) {
+			dir := filepath.Join(tmpDir, format)
+			err := os.MkdirAll(dir, 0755)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exportResources(dir, rs, exportOptions{format: format, inline: 16})
+
+			name := filepath.Join(dir, "winres."+format)
+			if _, err := ioutil.ReadFile(name); err != nil {
+				t.Fatal(err)
+			}
+			rs2 := &winres.ResourceSet{}
+			err = importResources(rs2, name, importOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs.Walk(func(typeID, resID winres.Identifier, langID uint16, data []byte) bool {
+				if !bytes.Equal(rs2.Get(typeID, resID, langID), data) {
+					t.Errorf("[%v][%v][%04X] differs", typeID, resID, langID)
+				}
+				return true
+			})
+		})
+	}
+}
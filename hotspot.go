@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errInvalidHotSpot = "invalid hot spot"
+	errHotSpotTwice   = "hot spot is given twice, with x and y, and with hotspot"
+	errNoHotSpot      = "cursor has no hot spot"
+	errHotSpotOutside = "hot spot is outside the image"
+)
+
+// hotSpotAnchors are named positions, in percents of the width and the height of an image.
+var hotSpotAnchors = map[string][2]float64{
+	"top-left":     {0, 0},
+	"top":          {50, 0},
+	"top-right":    {100, 0},
+	"left":         {0, 50},
+	"center":       {50, 50},
+	"right":        {100, 50},
+	"bottom-left":  {0, 100},
+	"bottom":       {50, 100},
+	"bottom-right": {100, 100},
+}
+
+// hotSpotCoord is a coordinate of a hot spot, either in pixels or in percents.
+type hotSpotCoord struct {
+	v       float64
+	percent bool
+}
+
+// hotSpotDef is the hot spot of a cursor image, as defined in json.
+// It can be applied to images of other sizes.
+type hotSpotDef struct {
+	x, y hotSpotCoord
+	// size is the size of the image that pixel coordinates refer to,
+	// it is set once the image is loaded
+	size image.Point
+}
+
+// readHotSpot reads the hot spot of a cursor image, from "x" and "y", or from "hotspot".
+//
+// It returns nil if the hot spot is not defined.
+func readHotSpot(c map[string]interface{}) (*hotSpotDef, error) {
+	x, xOK := c["x"]
+	y, yOK := c["y"]
+	s, hsOK := c["hotspot"]
+
+	switch {
+	case hsOK && (xOK || yOK):
+		return nil, errors.New(errHotSpotTwice)
+
+	case hsOK:
+		str, ok := s.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: %v", errInvalidHotSpot, s)
+		}
+		return parseHotSpot(str)
+
+	case xOK || yOK:
+		xf, xOK := x.(float64)
+		yf, yOK := y.(float64)
+		if !xOK || !yOK {
+			return nil, errors.New(errInvalidCursor)
+		}
+		return &hotSpotDef{x: hotSpotCoord{v: xf}, y: hotSpotCoord{v: yf}}, nil
+	}
+
+	return nil, nil
+}
+
+// parseHotSpot parses a named anchor such as "center", or two coordinates such as "50%,50%" or "12,4".
+func parseHotSpot(s string) (*hotSpotDef, error) {
+	if a, ok := hotSpotAnchors[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return &hotSpotDef{
+			x: hotSpotCoord{v: a[0], percent: true},
+			y: hotSpotCoord{v: a[1], percent: true},
+		}, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%s: %q", errInvalidHotSpot, s)
+	}
+	var coords [2]hotSpotCoord
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		coords[i].percent = strings.HasSuffix(p, "%")
+		v, err := strconv.ParseFloat(strings.TrimSuffix(p, "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q", errInvalidHotSpot, s)
+		}
+		coords[i].v = v
+	}
+	return &hotSpotDef{x: coords[0], y: coords[1]}, nil
+}
+
+// at returns the position of the hot spot in an image of the given size.
+//
+// Pixel coordinates are scaled when the image does not have the size they refer to.
+func (d *hotSpotDef) at(size image.Point) (winres.HotSpot, error) {
+	x := d.x.pixel(d.size.X, size.X)
+	y := d.y.pixel(d.size.Y, size.Y)
+	if x < 0 || y < 0 || x >= size.X || y >= size.Y {
+		return winres.HotSpot{}, fmt.Errorf("%s: (%d, %d) is not in %dx%d", errHotSpotOutside, x, y, size.X, size.Y)
+	}
+	return winres.HotSpot{X: uint16(x), Y: uint16(y)}, nil
+}
+
+// pixel returns the coordinate in an image of n pixels.
+//
+// A percentage p is the pixel at p*n/100, so that 50% of 32 is 16, and 100% is the last pixel.
+// A pixel v of an image of ref pixels is the same position, v*100/ref percents, so that pixel 16 of
+// a 32 pixels image becomes pixel 32 of a 64 pixels image, like "center".
+func (c hotSpotCoord) pixel(ref, n int) int {
+	switch {
+	case c.percent:
+		if c.v == 100 {
+			return n - 1
+		}
+		return int(math.Floor(c.v * float64(n) / 100))
+	case ref == n || ref <= 0:
+		return int(math.Floor(c.v))
+	}
+	return int(math.Floor(c.v * float64(n) / float64(ref)))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_hotSpotDef_at(t *testing.T) {
+	tests := []struct {
+		def  map[string]interface{}
+		ref  image.Point
+		size image.Point
+		want winres.HotSpot
+		err  string
+	}{
+		{def: map[string]interface{}{"x": 3.0, "y": 4.0}, ref: image.Pt(32, 32), size: image.Pt(32, 32), want: winres.HotSpot{X: 3, Y: 4}},
+		{def: map[string]interface{}{"x": 0.0, "y": 31.0}, ref: image.Pt(32, 32), size: image.Pt(64, 64), want: winres.HotSpot{X: 0, Y: 62}},
+		{def: map[string]interface{}{"x": 16.0, "y": 16.0}, ref: image.Pt(32, 32), size: image.Pt(64, 64), want: winres.HotSpot{X: 32, Y: 32}},
+		{def: map[string]interface{}{"hotspot": "center"}, size: image.Pt(64, 64), want: winres.HotSpot{X: 32, Y: 32}},
+		{def: map[string]interface{}{"x": 16.0, "y": 8.0}, ref: image.Pt(32, 32), size: image.Pt(48, 48), want: winres.HotSpot{X: 24, Y: 12}},
+		{def: map[string]interface{}{"hotspot": "center"}, size: image.Pt(32, 32), want: winres.HotSpot{X: 16, Y: 16}},
+		{def: map[string]interface{}{"hotspot": "Top-Left"}, size: image.Pt(48, 48), want: winres.HotSpot{}},
+		{def: map[string]interface{}{"hotspot": "bottom-right"}, size: image.Pt(48, 32), want: winres.HotSpot{X: 47, Y: 31}},
+		{def: map[string]interface{}{"hotspot": "50%, 0%"}, size: image.Pt(64, 64), want: winres.HotSpot{X: 32, Y: 0}},
+		{def: map[string]interface{}{"hotspot": "25%,10"}, size: image.Pt(64, 64), want: winres.HotSpot{X: 16, Y: 10}},
+		{def: map[string]interface{}{"hotspot": "3,4"}, ref: image.Pt(32, 32), size: image.Pt(32, 32), want: winres.HotSpot{X: 3, Y: 4}},
+		{def: map[string]interface{}{"x": 32.0, "y": 4.0}, ref: image.Pt(32, 32), size: image.Pt(32, 32), err: errHotSpotOutside + ": (32, 4) is not in 32x32"},
+		{def: map[string]interface{}{"hotspot": "150%,0%"}, size: image.Pt(32, 32), err: errHotSpotOutside + ": (48, 0) is not in 32x32"},
+		{def: map[string]interface{}{"hotspot": "-1,0"}, ref: image.Pt(32, 32), size: image.Pt(32, 32), err: errHotSpotOutside + ": (-1, 0) is not in 32x32"},
+		{def: map[string]interface{}{"hotspot": "middle"}, err: errInvalidHotSpot + `: "middle"`},
+		{def: map[string]interface{}{"hotspot": "1,2,3"}, err: errInvalidHotSpot + `: "1,2,3"`},
+		{def: map[string]interface{}{"hotspot": 12.0}, err: errInvalidHotSpot + ": 12"},
+		{def: map[string]interface{}{"hotspot": "center", "x": 1.0}, err: errHotSpotTwice},
+		{def: map[string]interface{}{"x": 1.0}, err: errInvalidCursor},
+	}
+	for _, tt := range tests {
+		hs, err := readHotSpot(tt.def)
+		if err == nil {
+			hs.size = tt.ref
+			var got winres.HotSpot
+			got, err = hs.at(tt.size)
+			if err == nil && got != tt.want {
+				t.Errorf("%v: got %v, want %v", tt.def, got, tt.want)
+			}
+		}
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%v: got error %v, want %q", tt.def, err, tt.err)
+		}
+	}
+}
+
+func Test_loadCursor_HotSpot(t *testing.T) {
+	// Only the first image has a hot spot, it is scaled for the second one
+	c, err := loadCursor("_testdata", []interface{}{
+		map[string]interface{}{"image": "icon.svg", "size": 32.0, "x": 31.0, "y": 16.0},
+		map[string]interface{}{"image": "icon.svg", "size": 64.0},
+		map[string]interface{}{"image": "icon.svg", "size": 48.0, "hotspot": "top-left"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err = c.SaveCUR(buf); err != nil {
+		t.Fatal(err)
+	}
+	images, err := readIconImages(buf.Bytes(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]winres.HotSpot{32: {X: 31, Y: 16}, 64: {X: 62, Y: 32}, 48: {}}
+	for _, img := range images {
+		if w := img.image.Bounds().Dx(); img.hotSpot != want[w] {
+			t.Errorf("%dx%d: got %v, want %v", w, w, img.hotSpot, want[w])
+		}
+	}
+
+	_, err = loadCursor("_testdata", []interface{}{
+		map[string]interface{}{"image": "icon.svg", "size": 32.0},
+	})
+	if err == nil || err.Error() != errNoHotSpot {
+		t.Errorf("expected %q, got %v", errNoHotSpot, err)
+	}
+
+	_, err = loadCursor("_testdata", []interface{}{
+		map[string]interface{}{"image": "cur-32x64.png", "x": 1.0, "y": 2.0},
+		map[string]interface{}{"image": "cur-32x64.png", "x": 40.0, "y": 2.0},
+	})
+	if want := "[1] " + errHotSpotOutside + ": (40, 2) is not in 32x64"; err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
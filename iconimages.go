@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errInvalidICO = "invalid icon or cursor data"
+	errInvalidDIB = "unsupported bitmap in icon or cursor"
+)
+
+// iconImage is one image of an ico or cur file.
+type iconImage struct {
+	image image.Image
+	// hotSpot is only set for cursors
+	hotSpot winres.HotSpot
+}
+
+// readIconImages decodes every image of an ico or cur file.
+func readIconImages(b []byte, cursor bool) ([]iconImage, error) {
+	if len(b) < 6 {
+		return nil, errors.New(errInvalidICO)
+	}
+	count := int(binary.LittleEndian.Uint16(b[4:]))
+	if len(b) < 6+16*count {
+		return nil, errors.New(errInvalidICO)
+	}
+
+	images := make([]iconImage, count)
+	for i := range images {
+		e := b[6+16*i:]
+		size, offset := int(binary.LittleEndian.Uint32(e[8:])), int(binary.LittleEndian.Uint32(e[12:]))
+		if offset+size > len(b) || offset+size < offset {
+			return nil, errors.New(errInvalidICO)
+		}
+		img, err := decodeIconImage(b[offset : offset+size])
+		if err != nil {
+			return nil, err
+		}
+		images[i].image = img
+		if cursor {
+			images[i].hotSpot = winres.HotSpot{
+				X: binary.LittleEndian.Uint16(e[4:]),
+				Y: binary.LittleEndian.Uint16(e[6:]),
+			}
+		}
+	}
+	return images, nil
+}
+
+// decodeIconImage decodes a png image, or a bitmap with its transparency mask.
+func decodeIconImage(data []byte) (image.Image, error) {
+	if len(data) > 8 && string(data[:8]) == "\x89PNG\r\n\x1a\n" {
+		return png.Decode(bytes.NewReader(data))
+	}
+	return decodeIconDIB(data)
+}
+
+// decodeIconDIB decodes the bitmap of an icon, which is made of a color image followed by a 1 bit mask.
+func decodeIconDIB(data []byte) (image.Image, error) {
+	var hdr struct {
+		Size        uint32
+		Width       int32
+		Height      int32
+		Planes      uint16
+		BitCount    uint16
+		Compression uint32
+		SizeImage   uint32
+		XPelsPerM   int32
+		YPelsPerM   int32
+		ClrUsed     uint32
+		ClrImp      uint32
+	}
+	if binary.Read(bytes.NewReader(data), binary.LittleEndian, &hdr) != nil || hdr.Size < 40 || hdr.Compression != 0 {
+		return nil, errors.New(errInvalidDIB)
+	}
+	w, h, bpp := int(hdr.Width), int(hdr.Height)/2, int(hdr.BitCount)
+	if w <= 0 || h <= 0 || w > 256 || h > 256 {
+		return nil, errors.New(errInvalidDIB)
+	}
+
+	var palette []color.NRGBA
+	pos := int(hdr.Size)
+	if bpp <= 8 {
+		n := int(hdr.ClrUsed)
+		if n == 0 || n > 1<<bpp {
+			n = 1 << bpp
+		}
+		if len(data) < pos+4*n {
+			return nil, errors.New(errInvalidDIB)
+		}
+		palette = make([]color.NRGBA, n)
+		for i := range palette {
+			palette[i] = color.NRGBA{R: data[pos+4*i+2], G: data[pos+4*i+1], B: data[pos+4*i], A: 255}
+		}
+		pos += 4 * n
+	}
+
+	stride := (w*bpp + 31) / 32 * 4
+	maskStride := (w + 31) / 32 * 4
+	if len(data) < pos+stride*h {
+		return nil, errors.New(errInvalidDIB)
+	}
+	xor, mask := data[pos:pos+stride*h], data[pos+stride*h:]
+	if len(mask) < maskStride*h {
+		mask = nil
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	hasAlpha := false
+	for y := 0; y < h; y++ {
+		row := xor[(h-1-y)*stride:]
+		for x := 0; x < w; x++ {
+			var c color.NRGBA
+			switch bpp {
+			case 1, 2, 4, 8:
+				bit := x * bpp
+				i := int(row[bit/8]>>(8-bpp-bit%8)) & (1<<bpp - 1)
+				if i < len(palette) {
+					c = palette[i]
+				}
+			case 16:
+				p := int(binary.LittleEndian.Uint16(row[2*x:]))
+				c = color.NRGBA{R: uint8(p >> 10 & 31 * 255 / 31), G: uint8(p >> 5 & 31 * 255 / 31), B: uint8(p & 31 * 255 / 31), A: 255}
+			case 24:
+				c = color.NRGBA{R: row[3*x+2], G: row[3*x+1], B: row[3*x], A: 255}
+			case 32:
+				c = color.NRGBA{R: row[4*x+2], G: row[4*x+1], B: row[4*x], A: row[4*x+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			default:
+				return nil, errors.New(errInvalidDIB)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// The mask gives transparency, unless the image has its own alpha channel
+	if hasAlpha || mask == nil {
+		return img, nil
+	}
+	for y := 0; y < h; y++ {
+		row := mask[(h-1-y)*maskStride:]
+		for x := 0; x < w; x++ {
+			a := uint8(255)
+			if row[x/8]>>(7-x%8)&1 != 0 {
+				a = 0
+			}
+			img.Pix[y*img.Stride+4*x+3] = a
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_exportResources_PNGImages(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	res := jsonDef{
+		"RT_GROUP_ICON": {
+			"APP": {"0000": []interface{}{"cur-32x64.png", "cur-64x128.png"}},
+			"#42": {"0409": "en.ico", "040C": "fr.ico"},
+		},
+		"RT_GROUP_CURSOR": {
+			"ARROW":  {"0000": []interface{}{map[string]interface{}{"image": "cur-64x128.png", "x": 58.0, "y": 34.0}}},
+			"NATIVE": {"0000": "cursor.cur"},
+		},
+	}
+	rs := &winres.ResourceSet{}
+	if err := importDef(rs, "_testdata", res); err != nil {
+		t.Fatal(err)
+	}
+
+	exportResources(tmpDir, rs, exportOptions{format: formatJSON, pngImages: true})
+
+	def, _, err := loadDef(filepath.Join(tmpDir, "winres.json"), importOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(def["RT_GROUP_ICON"]["APP"]["0000"], []interface{}{"APP_0000_128x128.png", "APP_0000_64x64.png"}) {
+		t.Errorf("wrong icon definition %v", def["RT_GROUP_ICON"]["APP"]["0000"])
+	}
+	if !reflect.DeepEqual(def["RT_GROUP_CURSOR"]["ARROW"]["0000"], []interface{}{
+		map[string]interface{}{"image": "ARROW_0000_128x128.png", "x": 58.0, "y": 34.0},
+	}) {
+		t.Errorf("wrong cursor definition %v", def["RT_GROUP_CURSOR"]["ARROW"]["0000"])
+	}
+
+	rs2 := &winres.ResourceSet{}
+	if err = importDef(rs2, tmpDir, def); err != nil {
+		t.Fatal(err)
+	}
+
+	// Native icons may contain bitmaps, which become png images, so only pixels are compared
+	for _, id := range []struct {
+		res  winres.Identifier
+		lang uint16
+	}{{winres.Name("APP"), 0}, {winres.ID(42), 0x409}, {winres.ID(42), 0x40C}} {
+		i1, _ := rs.GetIconTranslation(id.res, id.lang)
+		i2, _ := rs2.GetIconTranslation(id.res, id.lang)
+		compareIconImages(t, id.res, icoBytes(t, i1.SaveICO), icoBytes(t, i2.SaveICO), false)
+	}
+	for _, id := range []winres.Identifier{winres.Name("ARROW"), winres.Name("NATIVE")} {
+		c1, _ := rs.GetCursorTranslation(id, 0)
+		c2, _ := rs2.GetCursorTranslation(id, 0)
+		compareIconImages(t, id, icoBytes(t, c1.SaveCUR), icoBytes(t, c2.SaveCUR), true)
+	}
+}
+
+func icoBytes(t *testing.T, save func(w io.Writer) error) []byte {
+	buf := &bytes.Buffer{}
+	if err := save(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func compareIconImages(t *testing.T, id winres.Identifier, b1, b2 []byte, cursor bool) {
+	images1, err := readIconImages(b1, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images2, err := readIconImages(b2, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images1) != len(images2) {
+		t.Fatalf("%v: %d images, got %d", id, len(images1), len(images2))
+	}
+	for _, img1 := range images1 {
+		found := false
+		for _, img2 := range images2 {
+			if img1.hotSpot == img2.hotSpot && sameImage(img1.image, img2.image) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%v: %v image is different", id, img1.image.Bounds().Size())
+		}
+	}
+}
+
+func sameImage(a, b image.Image) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+	for y := a.Bounds().Min.Y; y < a.Bounds().Max.Y; y++ {
+		for x := a.Bounds().Min.X; x < a.Bounds().Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			// Hidden colors don't matter
+			if a1 != a2 || a1 != 0 && (r1 != r2 || g1 != g2 || b1 != b2) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func Test_exportResources_PNGImagesFallback(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	// A 16x16 bitmap with BI_BITFIELDS compression, which is not decoded
+	dib := &bytes.Buffer{}
+	binary.Write(dib, binary.LittleEndian, []uint32{40, 16, 32, 1 | 32<<16, 3, 0, 0, 0, 0, 0})
+	binary.Write(dib, binary.LittleEndian, []uint32{0xFF0000, 0xFF00, 0xFF})
+	dib.Write(make([]byte, 16*16*4+16*4))
+	ico := func(typ uint16) []byte {
+		b := &bytes.Buffer{}
+		binary.Write(b, binary.LittleEndian, []uint16{0, typ, 1})
+		binary.Write(b, binary.LittleEndian, []uint8{16, 16, 0, 0})
+		binary.Write(b, binary.LittleEndian, []uint16{1, 32})
+		binary.Write(b, binary.LittleEndian, []uint32{uint32(dib.Len()), 22})
+		b.Write(dib.Bytes())
+		return b.Bytes()
+	}
+
+	rs := &winres.ResourceSet{}
+	icon, err := winres.LoadICO(bytes.NewReader(ico(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.SetIcon(winres.Name("APP"), icon)
+	cursor, err := winres.LoadCUR(bytes.NewReader(ico(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.SetCursor(winres.Name("ARROW"), cursor)
+
+	exportResources(tmpDir, rs, exportOptions{format: formatJSON, pngImages: true})
+
+	def, _, err := loadDef(filepath.Join(tmpDir, "winres.json"), importOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := def["RT_GROUP_ICON"]["APP"]["0000"]; v != "APP_0000.ico" {
+		t.Errorf("expected an ico file, got %v", v)
+	}
+	if v := def["RT_GROUP_CURSOR"]["ARROW"]["0000"]; v != "ARROW_0000.cur" {
+		t.Errorf("expected a cur file, got %v", v)
+	}
+
+	rs2 := &winres.ResourceSet{}
+	if err = importDef(rs2, tmpDir, def); err != nil {
+		t.Fatal(err)
+	}
+	icon2, err := rs2.GetIcon(winres.Name("APP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(icoBytes(t, icon.SaveICO), icoBytes(t, icon2.SaveICO)) {
+		t.Error("icon is different")
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	// Decoders for icons and cursors
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	"github.com/nfnt/resize"
+	"github.com/tc-hib/winres"
+)
+
+const supportedImageFormats = "png, jpeg, gif, bmp, tiff, webp"
+
+const (
+	errUnknownFilter    = "unknown filter"
+	errInvalidSize      = "invalid icon size (expected 1 to 256)"
+	errTooManySizes     = "too many icon sizes (maximum 30)"
+	errICOResize        = "an ico file cannot be resized"
+	defaultResizeFilter = "lanczos2"
+)
+
+// resizeFilters are the resampling filters for making icons from a larger image.
+var resizeFilters = map[string]resize.InterpolationFunction{
+	"nearest":     resize.NearestNeighbor,
+	"bilinear":    resize.Bilinear,
+	"catmull-rom": resize.Bicubic,
+	"mitchell":    resize.MitchellNetravali,
+	"lanczos2":    resize.Lanczos2,
+	"lanczos":     resize.Lanczos3,
+}
+
+func filterNames() []string {
+	names := make([]string, 0, len(resizeFilters))
+	for k := range resizeFilters {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// decodeImage decodes an image in any of the supported formats.
+func decodeImage(r io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(r)
+	if err == image.ErrFormat {
+		return nil, fmt.Errorf("%v (supported formats: %s)", err, supportedImageFormats)
+	}
+	return img, err
+}
+
+// iconOptions tells how to make an icon from a single image.
+type iconOptions struct {
+	// sizes defaults to winres.DefaultIconSizes
+	sizes []int
+	// filter is the name of a resampling filter, it defaults to defaultResizeFilter
+	filter string
+	// overrides are images to use as they are, instead of resizing the main image
+	overrides map[int]string
+}
+
+// makeIcon makes an icon from an ico file, an svg file, or an image that is resized to each size.
+func makeIcon(name string, opt iconOptions) (*winres.Icon, error) {
+	filter := opt.filter
+	if filter == "" {
+		filter = defaultResizeFilter
+	}
+	interp, ok := resizeFilters[strings.ToLower(filter)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %q (expected %s)", errUnknownFilter, filter, strings.Join(filterNames(), ", "))
+	}
+
+	sizes := opt.sizes
+	if len(sizes) == 0 {
+		sizes = winres.DefaultIconSizes
+	}
+	sizes = append([]int{}, sizes...)
+	for _, s := range sortedOverrides(opt.overrides) {
+		if !containsInt(sizes, s) {
+			sizes = append(sizes, s)
+		}
+	}
+	if len(sizes) > 30 {
+		return nil, errors.New(errTooManySizes)
+	}
+	for _, s := range sizes {
+		if s < 1 || s > 256 {
+			return nil, fmt.Errorf("%s: %d", errInvalidSize, s)
+		}
+	}
+
+	var render func(size int) image.Image
+	switch {
+	case strings.ToLower(filepath.Ext(name)) == ".ico":
+		if len(opt.sizes) > 0 || len(opt.overrides) > 0 {
+			return nil, errors.New(errICOResize)
+		}
+		return loadICO(name)
+	case isSVG(name):
+		svg, err := loadSVG(name)
+		if err != nil {
+			return nil, err
+		}
+		render = func(size int) image.Image { return renderSVG(svg, size, size) }
+	default:
+		img, err := loadImage(name)
+		if err != nil {
+			return nil, err
+		}
+		render = func(size int) image.Image { return resizeImage(img, size, interp) }
+	}
+
+	images := make([]image.Image, len(sizes))
+	for i, s := range sizes {
+		o, ok := opt.overrides[s]
+		if !ok {
+			images[i] = render(s)
+			continue
+		}
+		img, err := loadOverride(o, s, interp)
+		if err != nil {
+			return nil, atPath(err, "overrides", strconv.Itoa(s))
+		}
+		images[i] = img
+	}
+	return winres.NewIconFromImages(images)
+}
+
+// loadOverride loads an image that replaces one size of an icon.
+// It is resized when its largest side does not have the right size.
+func loadOverride(name string, size int, interp resize.InterpolationFunction) (image.Image, error) {
+	if isSVG(name) {
+		return loadSVGImage(name, size)
+	}
+	img, err := loadImage(name)
+	if err != nil {
+		return nil, err
+	}
+	if sz := img.Bounds().Size(); sz.X == size && sz.Y <= size || sz.Y == size && sz.X <= size {
+		return img, nil
+	}
+	return resizeImage(img, size, interp), nil
+}
+
+func sortedOverrides(overrides map[int]string) []int {
+	sizes := make([]int, 0, len(overrides))
+	for s := range overrides {
+		sizes = append(sizes, s)
+	}
+	sort.Ints(sizes)
+	return sizes
+}
+
+func containsInt(list []int, n int) bool {
+	for _, x := range list {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
+
+// resizeImage resizes an image so that its largest side is size pixels long.
+func resizeImage(img image.Image, size int, interp resize.InterpolationFunction) image.Image {
+	var (
+		sz   = img.Bounds().Size()
+		w, h = size, size
+	)
+	if sz.X < sz.Y {
+		w = 0
+	} else if sz.X > sz.Y {
+		h = 0
+	}
+	return resize.Resize(uint(w), uint(h), img, interp)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+func Test_loadImage(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 24, 16))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+
+	encoders := map[string]func(w io.Writer, m image.Image) error{
+		"png":  png.Encode,
+		"jpg":  func(w io.Writer, m image.Image) error { return jpeg.Encode(w, m, nil) },
+		"gif":  func(w io.Writer, m image.Image) error { return gif.Encode(w, m, nil) },
+		"bmp":  bmp.Encode,
+		"tiff": func(w io.Writer, m image.Image) error { return tiff.Encode(w, m, nil) },
+	}
+	for ext, encode := range encoders {
+		name := filepath.Join(tmpDir, "image."+ext)
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = encode(f, img)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := loadImage(name)
+		if err != nil {
+			t.Errorf("%s: %v", ext, err)
+			continue
+		}
+		if m.Bounds() != img.Bounds() {
+			t.Errorf("%s: got bounds %v", ext, m.Bounds())
+		}
+	}
+
+	m, err := loadImage("_testdata/image.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Bounds().Empty() {
+		t.Error("empty webp image")
+	}
+
+	_, err = loadImage("_testdata/test.json")
+	if err == nil || !strings.Contains(err.Error(), "supported formats: "+supportedImageFormats) {
+		t.Errorf("expected a list of supported formats, got %v", err)
+	}
+}
+
+func Test_makeIcon(t *testing.T) {
+	tests := []struct {
+		name  string
+		opt   iconOptions
+		count int
+		err   string
+	}{
+		{name: "cur-64x128.png", count: 5},
+		{name: "cur-64x128.png", opt: iconOptions{sizes: []int{16, 20, 24, 32, 40, 48, 64, 256}, filter: "catmull-rom"}, count: 8},
+		{name: "cur-64x128.png", opt: iconOptions{sizes: []int{16}, filter: "Nearest"}, count: 1},
+		{name: "icon.svg", opt: iconOptions{sizes: []int{20, 40}}, count: 2},
+		{name: "en.ico"},
+		{name: "en.ico", opt: iconOptions{sizes: []int{16}}, err: errICOResize},
+		{name: "en.ico", opt: iconOptions{overrides: map[int]string{16: "_testdata/icon.svg"}}, err: errICOResize},
+		{name: "cur-64x128.png", opt: iconOptions{filter: "cubic"}, err: errUnknownFilter + `: "cubic" (expected bilinear, catmull-rom, lanczos, lanczos2, mitchell, nearest)`},
+		{name: "cur-64x128.png", opt: iconOptions{sizes: []int{16, 512}}, err: errInvalidSize + ": 512"},
+		{name: "cur-64x128.png", opt: iconOptions{sizes: make([]int, 31)}, err: errTooManySizes},
+		{name: "cur-64x128.png", opt: iconOptions{overrides: map[int]string{64: "_testdata/cur-32x64.png"}}, count: 5},
+		{name: "cur-64x128.png", opt: iconOptions{overrides: map[int]string{16: "_testdata/icon.svg", 24: "_testdata/icon.svg"}}, count: 6},
+		{name: "icon.svg", opt: iconOptions{sizes: []int{32}, overrides: map[int]string{16: "_testdata/icon.svg"}}, count: 2},
+		{name: "cur-64x128.png", opt: iconOptions{sizes: []int{32}, overrides: map[int]string{20: "_testdata/cur-32x64.png"}}, count: 2},
+		{
+			name: "cur-64x128.png",
+			opt:  iconOptions{overrides: map[int]string{16: "_testdata/missing.png"}},
+			err:  "[overrides][16] open _testdata/missing.png: no such file or directory",
+		},
+	}
+	for _, tt := range tests {
+		icon, err := makeIcon(filepath.Join("_testdata", tt.name), tt.opt)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s %+v: expected error %q, got %v", tt.name, tt.opt, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %+v: %v", tt.name, tt.opt, err)
+			continue
+		}
+		if tt.count == 0 {
+			continue
+		}
+		buf := &bytes.Buffer{}
+		icon.SaveICO(buf)
+		if n := int(binary.LittleEndian.Uint16(buf.Bytes()[4:])); n != tt.count {
+			t.Errorf("%s %+v: expected %d images, got %d", tt.name, tt.opt, tt.count, n)
+		}
+	}
+}
+
+func Test_loadIcon_Object(t *testing.T) {
+	_, err := loadIcon("_testdata", map[string]interface{}{
+		"image":  "cur-64x128.png",
+		"sizes":  []interface{}{16.0, 24.0},
+		"filter": "nearest",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = loadIcon("_testdata", map[string]interface{}{"sizes": []interface{}{16.0}})
+	if err == nil || err.Error() != errInvalidIcon {
+		t.Errorf("expected %q, got %v", errInvalidIcon, err)
+	}
+
+	_, err = loadIcon("_testdata", map[string]interface{}{"image": "cur-64x128.png", "sizes": []interface{}{"16"}})
+	if err == nil || err.Error() != "[sizes][0] "+errInvalidIcon {
+		t.Errorf("expected %q, got %v", "[sizes][0] "+errInvalidIcon, err)
+	}
+
+	_, err = loadIcon("_testdata", map[string]interface{}{
+		"source":    "cur-64x128.png",
+		"overrides": map[string]interface{}{"64": "cur-32x64.png", "24": "icon.svg"},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, x := range []map[string]interface{}{
+		{"image": "cur-64x128.png", "source": "cur-64x128.png"},
+		{"image": 5.0, "source": "cur-64x128.png"},
+		{"source": 5.0},
+	} {
+		_, err = loadIcon("_testdata", x)
+		if err == nil || err.Error() != errInvalidIcon {
+			t.Errorf("%v: expected %q, got %v", x, errInvalidIcon, err)
+		}
+	}
+
+	_, err = loadIcon("_testdata", map[string]interface{}{
+		"source":    "cur-64x128.png",
+		"overrides": map[string]interface{}{"small": "cur-32x64.png"},
+	})
+	if err == nil || err.Error() != "[overrides][small] "+errInvalidIcon {
+		t.Errorf("expected %q, got %v", "[overrides][small] "+errInvalidIcon, err)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errInvalidInline = `invalid inline data: expected one of "text", "base64" or "hex"`
+	errUnknownEnc    = "unknown encoding"
+)
+
+const (
+	encodingUTF8    = "utf-8"
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+	encodingLatin1  = "latin1"
+)
+
+// loadInline decodes data that is given in the json file instead of a file name.
+func loadInline(x map[string]interface{}) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+		n    int
+	)
+
+	for k, v := range x {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New(errInvalidInline)
+		}
+		switch k {
+		case "text":
+			enc, _ := x["encoding"].(string)
+			data, err = encodeText(s, enc)
+		case "base64":
+			data, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+		case "hex":
+			data, err = hex.DecodeString(strings.Join(strings.Fields(s), ""))
+		case "encoding":
+			if _, isText := x["text"]; !isText {
+				return nil, errors.New(errInvalidInline)
+			}
+			continue
+		default:
+			return nil, errors.New(errInvalidInline)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s data: %v", k, err)
+		}
+		n++
+	}
+
+	if n != 1 {
+		return nil, errors.New(errInvalidInline)
+	}
+	return data, nil
+}
+
+func encodeText(s string, encoding string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "", encodingUTF8, "utf8":
+		return []byte(s), nil
+	case encodingUTF16LE, "utf-16":
+		buf := &bytes.Buffer{}
+		binary.Write(buf, binary.LittleEndian, utf16.Encode([]rune(s)))
+		return buf.Bytes(), nil
+	case encodingUTF16BE:
+		buf := &bytes.Buffer{}
+		binary.Write(buf, binary.BigEndian, utf16.Encode([]rune(s)))
+		return buf.Bytes(), nil
+	case encodingLatin1:
+		var b []byte
+		for _, r := range s {
+			if r > 0xFF {
+				return nil, fmt.Errorf("%q can't be encoded in latin1", r)
+			}
+			b = append(b, byte(r))
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("%s: %q", errUnknownEnc, encoding)
+}
+
+// inlineValue returns the json form of inline data.
+// Text is preferred, in UTF-8 or UTF-16LE, then base64.
+//
+// Binary data is often valid UTF-16, so UTF-16 text must be mostly ASCII.
+func inlineValue(data []byte) map[string]interface{} {
+	if utf8.Valid(data) && isText(string(data)) {
+		return map[string]interface{}{"text": string(data)}
+	}
+	if len(data) > 0 && len(data)%2 == 0 {
+		u := make([]uint16, len(data)/2)
+		ascii := 0
+		for i := range u {
+			u[i] = binary.LittleEndian.Uint16(data[i*2:])
+			if u[i] < 0x80 {
+				ascii++
+			}
+		}
+		s := string(utf16.Decode(u))
+		if b, _ := encodeText(s, encodingUTF16LE); ascii*2 >= len(u) && bytes.Equal(b, data) && isText(s) {
+			return map[string]interface{}{"text": s, "encoding": encodingUTF16LE}
+		}
+	}
+	return map[string]interface{}{"base64": base64.StdEncoding.EncodeToString(data)}
+}
+
+// isText tells if a string is printable, apart from usual white space and trailing null characters.
+func isText(s string) bool {
+	s = strings.TrimRight(s, "\x00")
+	for _, r := range s {
+		if r != '\t' && r != '\n' && r != '\r' && !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isInlinable tells if a resource type accepts inline data, because it is not decoded in the json file.
+func isInlinable(typeID winres.Identifier) bool {
+	switch typeID {
+	case winres.RT_CURSOR, winres.RT_ICON, winres.RT_GROUP_CURSOR, winres.RT_GROUP_ICON,
+		winres.RT_BITMAP, winres.RT_STRING, winres.RT_VERSION, winres.RT_MANIFEST,
+		winres.RT_MESSAGETABLE, winres.RT_ACCELERATOR, winres.RT_MENU, winres.RT_DIALOG,
+		winres.RT_FONT, winres.RT_ANICURSOR, winres.RT_ANIICON:
+		return false
+	}
+	return true
+}
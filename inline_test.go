@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_loadInline(t *testing.T) {
+	tests := []struct {
+		name string
+		x    map[string]interface{}
+		want []byte
+	}{
+		{"text", map[string]interface{}{"text": "héllo"}, []byte("h\xC3\xA9llo")},
+		{"utf-8", map[string]interface{}{"text": "é", "encoding": "UTF-8"}, []byte{0xC3, 0xA9}},
+		{"utf-16le", map[string]interface{}{"text": "hé€", "encoding": "utf-16le"}, []byte{'h', 0, 0xE9, 0, 0xAC, 0x20}},
+		{"utf-16be", map[string]interface{}{"text": "h€", "encoding": "utf-16be"}, []byte{0, 'h', 0x20, 0xAC}},
+		{"latin1", map[string]interface{}{"text": "hé", "encoding": "latin1"}, []byte{'h', 0xE9}},
+		{"base64", map[string]interface{}{"base64": "AAEC\n/w=="}, []byte{0, 1, 2, 0xFF}},
+		{"hex", map[string]interface{}{"hex": "00 01 02 ff"}, []byte{0, 1, 2, 0xFF}},
+		{"empty", map[string]interface{}{"text": ""}, []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadInline(tt.x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("loadInline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_loadInline_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		x    map[string]interface{}
+		err  string
+	}{
+		{"nothing", map[string]interface{}{}, errInvalidInline},
+		{"two values", map[string]interface{}{"text": "a", "hex": "00"}, errInvalidInline},
+		{"unknown key", map[string]interface{}{"file": "a"}, errInvalidInline},
+		{"not a string", map[string]interface{}{"hex": 42.0}, errInvalidInline},
+		{"encoding only", map[string]interface{}{"encoding": "latin1"}, errInvalidInline},
+		{"encoding without text", map[string]interface{}{"encoding": "latin1", "hex": "00"}, errInvalidInline},
+		{"unknown encoding", map[string]interface{}{"text": "a", "encoding": "ebcdic"}, `invalid text data: unknown encoding: "ebcdic"`},
+		{"latin1", map[string]interface{}{"text": "€", "encoding": "latin1"}, `invalid text data: '€' can't be encoded in latin1`},
+		{"hex", map[string]interface{}{"hex": "0"}, "invalid hex data: encoding/hex: odd length hex string"},
+		{"base64", map[string]interface{}{"base64": "!"}, "invalid base64 data: illegal base64 data at input byte 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadInline(tt.x)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("loadInline() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_inlineValue(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want map[string]interface{}
+	}{
+		{[]byte("key=value\r\n"), map[string]interface{}{"text": "key=value\r\n"}},
+		{[]byte("text\x00"), map[string]interface{}{"text": "text\x00"}},
+		{[]byte{'h', 0, 0xE9, 0, 0, 0}, map[string]interface{}{"text": "hé\x00", "encoding": encodingUTF16LE}},
+		{[]byte{0, 1, 2, 0xFF}, map[string]interface{}{"base64": "AAEC/w=="}},
+		// An unpaired surrogate can't be decoded
+		{[]byte{0x00, 0xD8}, map[string]interface{}{"base64": "ANg="}},
+	}
+	for _, tt := range tests {
+		got := inlineValue(tt.data)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("inlineValue(%v) = %v, want %v", tt.data, got, tt.want)
+		}
+		back, err := loadInline(got)
+		if err != nil || !bytes.Equal(back, tt.data) {
+			t.Errorf("loadInline(%v) = %v, %v", got, back, err)
+		}
+	}
+}
+
+func Test_exportResources_Inline(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	rs := &winres.ResourceSet{}
+	rs.Set(winres.RT_RCDATA, winres.Name("SMALL"), 0x409, []byte("small"))
+	rs.Set(winres.RT_RCDATA, winres.Name("BIG"), 0x409, bytes.Repeat([]byte("big"), 10))
+	rs.Set(winres.Name("CUSTOM"), winres.ID(1), 0x409, []byte{1, 2, 3})
+	rs.Set(winres.RT_BITMAP, winres.ID(1), 0x409, []byte{1, 2, 3})
+
+	exportResources(tmpDir, rs, exportOptions{format: formatJSON, inline: 16})
+
+	b, err := ioutil.ReadFile(filepath.Join(tmpDir, "winres.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := jsonDef{}
+	json.Unmarshal(b, &res)
+
+	if !reflect.DeepEqual(res["RT_RCDATA"]["SMALL"]["0409"], map[string]interface{}{"text": "small"}) {
+		t.Errorf("SMALL = %v", res["RT_RCDATA"]["SMALL"]["0409"])
+	}
+	if res["RT_RCDATA"]["BIG"]["0409"] != "RT_RCDATA_BIG_0409.bin" {
+		t.Errorf("BIG = %v", res["RT_RCDATA"]["BIG"]["0409"])
+	}
+	if !reflect.DeepEqual(res["CUSTOM"]["#1"]["0409"], map[string]interface{}{"base64": "AQID"}) {
+		t.Errorf("CUSTOM = %v", res["CUSTOM"]["#1"]["0409"])
+	}
+	if _, ok := res["RT_BITMAP"]["#1"]["0409"].(string); !ok {
+		t.Errorf("RT_BITMAP = %v", res["RT_BITMAP"]["#1"]["0409"])
+	}
+
+	rs2 := &winres.ResourceSet{}
+	err = importResources(rs2, filepath.Join(tmpDir, "winres.json"), importOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Walk(func(typeID, resID winres.Identifier, langID uint16, data []byte) bool {
+		if typeID != winres.RT_BITMAP && !bytes.Equal(rs2.Get(typeID, resID, langID), data) {
+			t.Errorf("[%v][%v][%04X] differs", typeID, resID, langID)
+		}
+		return true
+	})
+}
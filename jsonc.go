@@ -0,0 +1,66 @@
+package main
+
+import "errors"
+
+const errUnterminatedComment = "unterminated comment"
+
+// stripJSONC turns JSON with comments and trailing commas into standard JSON.
+//
+// Comments and trailing commas are replaced with spaces, so that offsets,
+// lines and columns in the result still match the original file.
+func stripJSONC(b []byte) ([]byte, error) {
+	out := make([]byte, len(b))
+	copy(out, b)
+
+	for i := 0; i < len(out); i++ {
+		switch out[i] {
+		case '"':
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+		case '/':
+			if i+1 >= len(out) {
+				break
+			}
+			switch out[i+1] {
+			case '/':
+				for ; i < len(out) && out[i] != '\n'; i++ {
+					out[i] = ' '
+				}
+			case '*':
+				out[i], out[i+1] = ' ', ' '
+				for i += 2; i+1 < len(out) && !(out[i] == '*' && out[i+1] == '/'); i++ {
+					blank(out, i)
+				}
+				if i+1 >= len(out) {
+					return nil, errors.New(errUnterminatedComment)
+				}
+				out[i], out[i+1] = ' ', ' '
+				i++
+			}
+		case ']', '}':
+			j := i - 1
+			for j >= 0 && isJSONSpace(out[j]) {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out[j] = ' '
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// blank replaces a character with a space, but keeps line breaks.
+func blank(b []byte, i int) {
+	if b[i] != '\n' && b[i] != '\r' {
+		b[i] = ' '
+	}
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
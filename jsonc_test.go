@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_stripJSONC(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"line comment", "{\n  // comment\n  \"a\": 1 // end\n}", "{\n            \n  \"a\": 1       \n}"},
+		{"block comment", "{/* a\nb */\"a\": 1}", "{    \n    \"a\": 1}"},
+		{"trailing commas", "{\"a\": [1, 2,\n],}", "{\"a\": [1, 2 \n] }"},
+		{"comma before comment", "[1, // one\n]", "[1        \n]"},
+		{"strings", `{"a": "// not a comment", "b": "/* no */", "c": "\"]\",}"}`, `{"a": "// not a comment", "b": "/* no */", "c": "\"]\",}"}`},
+		{"slash", `{"a": 1 /}`, `{"a": 1 /}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stripJSONC([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("stripJSONC() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	_, err := stripJSONC([]byte("{} /* unterminated *"))
+	if err == nil || err.Error() != errUnterminatedComment {
+		t.Errorf("stripJSONC() error = %v", err)
+	}
+}
+
+func Test_initJSON(t *testing.T) {
+	b, err := stripJSONC([]byte(initJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(b) {
+		t.Error("initJSON is not valid json")
+	}
+}
+
+func Test_importResources_Strict(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	name := filepath.Join(tmpDir, "winres.json")
+	err := ioutil.WriteFile(name, []byte(`{
+  // Temporarily disabled
+  // "RT_RCDATA": {"A": {"0409": "a.bin"}},
+  "RT_RCDATA": {
+    "B": {"0409": {"text": "b"},},
+  },
+}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := &winres.ResourceSet{}
+	err = importResources(rs, name, importOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rs.Get(winres.RT_RCDATA, winres.Name("B"), 0x409)) != "b" {
+		t.Error("RT_RCDATA B is missing")
+	}
+
+	err = importResources(&winres.ResourceSet{}, name, importOptions{strict: true})
+	if err == nil || !strings.Contains(err.Error(), "invalid character '/'") {
+		t.Errorf("importResources() error = %v", err)
+	}
+}
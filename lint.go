@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tc-hib/winres"
+)
+
+const warnOpaqueIcon = "icon has no transparent pixel, it may have no alpha channel"
+
+// standardIconSizes are the sizes Windows looks for: small icons, large icons, Explorer, and large thumbnails.
+var standardIconSizes = []int{16, 32, 48, 256}
+
+// lintIcons checks the icons of a definition, once they are imported in rs.
+//
+// It returns warnings about missing standard sizes, opaque icons, non-square images,
+// and images that are upscaled.
+func lintIcons(rs *winres.ResourceSet, dir string, res jsonDef) error {
+	var warnings errorList
+	for _, r := range sortedRes(res["RT_GROUP_ICON"]) {
+		for _, l := range sortedLang(r.langs) {
+			_, resID, langID, err := idsFromStrings("RT_GROUP_ICON", r.id, l.id)
+			if err != nil {
+				continue
+			}
+			icon, err := rs.GetIconTranslation(resID, langID)
+			if err != nil {
+				continue
+			}
+			var w errorList
+			w.add(lintIcon(icon))
+			w.add(lintIconSources(dir, l.data))
+			warnings.add(atPath(w.err(), "RT_GROUP_ICON", r.id, l.id))
+		}
+	}
+	return warnings.err()
+}
+
+// lintIcon checks the images of an icon.
+func lintIcon(icon *winres.Icon) error {
+	buf := &bytes.Buffer{}
+	if err := icon.SaveICO(buf); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+
+	var (
+		sizes       = make(map[int]bool)
+		transparent bool
+	)
+	for i := 0; i < int(binary.LittleEndian.Uint16(b[4:])); i++ {
+		e := b[6+16*i:]
+		w, h := int(e[0]), int(e[1])
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+		if w == h {
+			sizes[w] = true
+		}
+		size, offset := binary.LittleEndian.Uint32(e[8:]), binary.LittleEndian.Uint32(e[12:])
+		if !transparent && int(offset+size) <= len(b) {
+			transparent = hasAlpha(b[offset : offset+size])
+		}
+	}
+
+	var (
+		warnings errorList
+		missing  []string
+	)
+	for _, s := range standardIconSizes {
+		if !sizes[s] {
+			missing = append(missing, fmt.Sprintf("%dx%d", s, s))
+		}
+	}
+	if len(missing) > 0 {
+		warnings.add(fmt.Errorf("icon has no %s image", strings.Join(missing, ", ")))
+	}
+	if !transparent {
+		warnings.add(errors.New(warnOpaqueIcon))
+	}
+	return warnings.err()
+}
+
+// hasAlpha tells if an image of an icon has transparent pixels.
+//
+// Images are either png files, or bitmaps without a file header.
+// Bitmaps get their transparency from their alpha channel, or from their AND mask when they have none.
+func hasAlpha(data []byte) bool {
+	var (
+		img image.Image
+		err error
+	)
+	if len(data) > 8 && string(data[:8]) == "\x89PNG\r\n\x1a\n" {
+		img, err = png.Decode(bytes.NewReader(data))
+	} else {
+		img, err = decodeIconDIB(data)
+	}
+	if err != nil {
+		return false
+	}
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque()
+	}
+	return true
+}
+
+// lintIconSources checks the images an icon is made from.
+func lintIconSources(dir string, x interface{}) error {
+	var warnings errorList
+
+	switch x := x.(type) {
+	case string:
+		warnings.add(lintSourceImage(filepath.Join(dir, x), x, winres.DefaultIconSizes))
+
+	case []interface{}:
+		for i := range x {
+			if f, ok := x[i].(string); ok {
+				warnings.add(atPath(lintSourceImage(filepath.Join(dir, f), f, nil), strconv.Itoa(i)))
+			}
+		}
+
+	case map[string]interface{}:
+		name, key, opt, err := iconObject(dir, x)
+		if err != nil {
+			return nil
+		}
+		sizes := opt.sizes
+		if len(sizes) == 0 {
+			sizes = winres.DefaultIconSizes
+		}
+		var resized []int
+		for _, s := range sizes {
+			if _, ok := opt.overrides[s]; !ok {
+				resized = append(resized, s)
+			}
+		}
+		display, err := filepath.Rel(dir, name)
+		if err != nil {
+			display = name
+		}
+		warnings.add(atPath(lintSourceImage(name, display, resized), key))
+		// Keys are read as iconObject reads them, so "016" is the 16x16 override
+		overrides, _ := x["overrides"].(map[string]interface{})
+		for _, o := range sortedLang(overrides) {
+			s, err := strconv.Atoi(o.id)
+			f, _ := o.data.(string)
+			if err != nil || opt.overrides[s] != filepath.Join(dir, f) {
+				continue
+			}
+			warnings.add(atPath(lintSourceImage(opt.overrides[s], f, []int{s}), "overrides", o.id))
+		}
+	}
+
+	return warnings.err()
+}
+
+// lintSourceImage checks that an image is square, and large enough for each size it is resized to.
+//
+// Ico and svg files are not checked.
+func lintSourceImage(name string, display string, sizes []int) error {
+	if isSVG(name) || strings.ToLower(filepath.Ext(name)) == ".ico" {
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil
+	}
+
+	var warnings errorList
+	if cfg.Width != cfg.Height {
+		warnings.add(fmt.Errorf("%s is not square (%dx%d)", display, cfg.Width, cfg.Height))
+	}
+	largest := cfg.Width
+	if cfg.Height > largest {
+		largest = cfg.Height
+	}
+	max := 0
+	for _, s := range sizes {
+		if s > max {
+			max = s
+		}
+	}
+	if max > largest {
+		warnings.add(fmt.Errorf("%s is upscaled from %dx%d to %dx%d", display, cfg.Width, cfg.Height, max, max))
+	}
+	return warnings.err()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_lintIcons(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	writePNG := func(name string, w, h int, opaque bool) {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for i := range img.Pix {
+			img.Pix[i] = 255
+		}
+		if !opaque {
+			img.Pix[3] = 0
+		}
+		f, err := os.Create(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err = png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writePNG("big.png", 256, 256, false)
+	writePNG("small.png", 64, 64, false)
+	writePNG("opaque.png", 256, 256, true)
+	writePNG("wide.png", 256, 128, false)
+	writePNG("16.png", 16, 16, false)
+	copyFile(t, "_testdata/icon.svg", filepath.Join(tmpDir, "icon.svg"))
+
+	res := jsonDef{
+		"RT_GROUP_ICON": {
+			"GOOD": {"0000": "big.png"},
+			"SVG":  {"0000": "icon.svg"},
+			"OBJ":  {"0000": map[string]interface{}{"image": "small.png", "sizes": []interface{}{16.0, 32.0, 48.0, 64.0}}},
+			"UP":   {"0000": map[string]interface{}{"image": "small.png", "overrides": map[string]interface{}{"16": "16.png", "032": "16.png"}}},
+			"LIST": {"0000": []interface{}{"opaque.png", "wide.png"}},
+		},
+	}
+	rs := &winres.ResourceSet{}
+	if err := importDef(rs, tmpDir, res); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"[RT_GROUP_ICON][LIST][0000] icon has no 16x16, 32x32, 48x48 image",
+		"[RT_GROUP_ICON][LIST][0000][1] wide.png is not square (256x128)",
+		"[RT_GROUP_ICON][OBJ][0000] icon has no 256x256 image",
+		"[RT_GROUP_ICON][UP][0000][image] small.png is upscaled from 64x64 to 256x256",
+		"[RT_GROUP_ICON][UP][0000][overrides][032] 16.png is upscaled from 16x16 to 32x32",
+	}
+	warnings, _ := lintIcons(rs, tmpDir, res).(errorList)
+	for i := 0; i < len(warnings) || i < len(want); i++ {
+		var got, w string
+		if i < len(warnings) {
+			got = warnings[i].Error()
+		}
+		if i < len(want) {
+			w = want[i]
+		}
+		if got != w {
+			t.Errorf("warning %d = %q, want %q", i, got, w)
+		}
+	}
+
+	// Invalid definitions are reported by importDef, and ignored here
+	res = jsonDef{"RT_GROUP_ICON": {"APP": {"0000": map[string]interface{}{"image": 5.0, "source": "small.png"}}}}
+	if err := lintIcons(&winres.ResourceSet{}, tmpDir, res); err != nil {
+		t.Error(err)
+	}
+
+	res = jsonDef{"RT_GROUP_ICON": {"APP": {"0000": map[string]interface{}{"source": "small.png"}}}}
+	rs = &winres.ResourceSet{}
+	if err := importDef(rs, tmpDir, res); err != nil {
+		t.Fatal(err)
+	}
+	warnings, _ = lintIcons(rs, tmpDir, res).(errorList)
+	if want := "[RT_GROUP_ICON][APP][0000][source] small.png is upscaled from 64x64 to 256x256"; len(warnings) == 0 || warnings[len(warnings)-1].Error() != want {
+		t.Errorf("expected %q, got %v", want, warnings)
+	}
+
+	res = jsonDef{"RT_GROUP_ICON": {"APP": {"0000": "opaque.png"}}}
+	rs = &winres.ResourceSet{}
+	if err := importDef(rs, tmpDir, res); err != nil {
+		t.Fatal(err)
+	}
+	err := lintIcons(rs, tmpDir, res)
+	if err == nil || err.Error() != "[RT_GROUP_ICON][APP][0000] "+warnOpaqueIcon {
+		t.Errorf("expected %q, got %v", warnOpaqueIcon, err)
+	}
+}
+
+func Test_lintIcon_Mask(t *testing.T) {
+	// makeICO makes a 16x16 icon of 24 bits per pixel, whose first pixel may be transparent in the AND mask
+	makeICO := func(transparent bool) *winres.Icon {
+		dib := &bytes.Buffer{}
+		binary.Write(dib, binary.LittleEndian, struct {
+			Size     uint32
+			Width    int32
+			Height   int32
+			Planes   uint16
+			BitCount uint16
+			_        [24]byte
+		}{Size: 40, Width: 16, Height: 32, Planes: 1, BitCount: 24})
+		dib.Write(make([]byte, 48*16))
+		mask := make([]byte, 4*16)
+		if transparent {
+			mask[len(mask)-4] = 0x80
+		}
+		dib.Write(mask)
+
+		ico := &bytes.Buffer{}
+		binary.Write(ico, binary.LittleEndian, [3]uint16{0, 1, 1})
+		binary.Write(ico, binary.LittleEndian, struct {
+			Width, Height, Colors, Reserved uint8
+			Planes, BitCount                uint16
+			Size, Offset                    uint32
+		}{16, 16, 0, 0, 1, 24, uint32(dib.Len()), 22})
+		ico.Write(dib.Bytes())
+
+		icon, err := winres.LoadICO(bytes.NewReader(ico.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return icon
+	}
+
+	want := "icon has no 32x32, 48x48, 256x256 image"
+	if err := lintIcon(makeICO(true)); err == nil || err.Error() != want {
+		t.Errorf("lintIcon() = %v, want %q", err, want)
+	}
+	want += "\n" + warnOpaqueIcon
+	if err := lintIcon(makeICO(false)); err == nil || err.Error() != want {
+		t.Errorf("lintIcon() = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defError is an error in a resource definition, with its location.
+type defError struct {
+	// path is made of the resource type, name, language, then keys or indexes inside the resource
+	path []string
+	// file, line and column are only known after calling locateErrors
+	file string
+	line int
+	col  int
+	err  error
+}
+
+func (e *defError) Error() string {
+	s := ""
+	if e.line > 0 {
+		s = fmt.Sprintf("%s:%d:%d: ", e.file, e.line, e.col)
+	}
+	if len(e.path) > 0 {
+		s += "[" + strings.Join(e.path, "][") + "] "
+	}
+	return s + e.err.Error()
+}
+
+// errorList is used to report several errors at once.
+type errorList []error
+
+func (l errorList) Error() string {
+	s := make([]string, len(l))
+	for i := range l {
+		s[i] = l[i].Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// add appends an error, or the content of another list.
+func (l *errorList) add(err error) {
+	switch err := err.(type) {
+	case nil:
+	case errorList:
+		*l = append(*l, err...)
+	default:
+		*l = append(*l, err)
+	}
+}
+
+// err returns nil when the list is empty.
+func (l errorList) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// atPath prefixes the location of an error, or of each error of a list, with a path.
+func atPath(err error, path ...string) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case errorList:
+		for i := range e {
+			e[i] = atPath(e[i], path...)
+		}
+		return e
+	case *defError:
+		e.path = append(append([]string{}, path...), e.path...)
+		return e
+	}
+	return &defError{path: append([]string{}, path...), err: err}
+}
+
+// position is a line and a column, starting at 1.
+type position struct {
+	line, col int
+}
+
+// locateErrors adds the file name, line and column to errors found in a definition file.
+//
+// The longest part of the path that exists in the file gives the location.
+// Positions are only known in json and yaml files.
+// Errors of a list are sorted by location.
+func locateErrors(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	pos := defPositions(name)
+	var locate func(err error)
+	locate = func(err error) {
+		switch e := err.(type) {
+		case errorList:
+			for i := range e {
+				locate(e[i])
+			}
+		case *defError:
+			for n := len(e.path); n > 0; n-- {
+				if p, ok := pos[pathKey(e.path[:n])]; ok {
+					e.file, e.line, e.col = name, p.line, p.col
+					return
+				}
+			}
+		}
+	}
+	locate(err)
+	if l, ok := err.(errorList); ok {
+		sortErrors(l)
+	}
+	return err
+}
+
+// sortErrors sorts errors by position, or by path when positions are unknown.
+// Errors without a path come first.
+func sortErrors(errs errorList) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		a, ok := errs[i].(*defError)
+		if !ok {
+			return true
+		}
+		b, ok := errs[j].(*defError)
+		if !ok {
+			return false
+		}
+		if a.line != b.line {
+			return a.line < b.line
+		}
+		if a.col != b.col {
+			return a.col < b.col
+		}
+		return pathKey(a.path) < pathKey(b.path)
+	})
+}
+
+func pathKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// defPositions reads a definition file and returns the position of each key or array element, by path.
+func defPositions(name string) map[string]position {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	switch defFormat(name) {
+	case formatJSON:
+		b, err = stripJSONC(b)
+		if err != nil {
+			return nil
+		}
+		pos := make(map[string]position)
+		if jsonPositions(json.NewDecoder(bytes.NewReader(b)), b, nil, pos) != nil {
+			return nil
+		}
+		return pos
+	case formatYAML:
+		var doc yaml.Node
+		if yaml.Unmarshal(b, &doc) != nil || len(doc.Content) == 0 {
+			return nil
+		}
+		pos := make(map[string]position)
+		yamlPositions(doc.Content[0], nil, pos)
+		return pos
+	}
+	return nil
+}
+
+func jsonPositions(dec *json.Decoder, b []byte, path []string, pos map[string]position) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	d, ok := tok.(json.Delim)
+	if !ok || d != '{' && d != '[' {
+		return nil
+	}
+
+	for i := 0; dec.More(); i++ {
+		start := int(dec.InputOffset())
+		for start < len(b) && strings.IndexByte(" \t\r\n,", b[start]) >= 0 {
+			start++
+		}
+		key := strconv.Itoa(i)
+		if d == '{' {
+			tok, err = dec.Token()
+			if err != nil {
+				return err
+			}
+			key = tok.(string)
+		}
+		p := append(append([]string{}, path...), key)
+		pos[pathKey(p)] = offsetPosition(b, start)
+		err = jsonPositions(dec, b, p, pos)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// offsetPosition converts an offset to a line and a column.
+func offsetPosition(b []byte, offset int) position {
+	if offset > len(b) {
+		offset = len(b)
+	}
+	line := bytes.Count(b[:offset], []byte{'\n'}) + 1
+	col := offset - bytes.LastIndexByte(b[:offset], '\n')
+	return position{line, col}
+}
+
+func yamlPositions(n *yaml.Node, path []string, pos map[string]position) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			p := append(append([]string{}, path...), k.Value)
+			pos[pathKey(p)] = position{k.Line, k.Column}
+			yamlPositions(n.Content[i+1], p, pos)
+		}
+	case yaml.SequenceNode:
+		for i, v := range n.Content {
+			p := append(append([]string{}, path...), strconv.Itoa(i))
+			pos[pathKey(p)] = position{v.Line, v.Column}
+			yamlPositions(v, p, pos)
+		}
+	}
+}
+
+// syntaxPosition adds the line and column to json syntax errors.
+func syntaxPosition(name string, b []byte, err error) error {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return err
+	}
+	// The offset is after the character that caused the error
+	if offset > 0 {
+		offset--
+	}
+	p := offsetPosition(b, int(offset))
+	return fmt.Errorf("%s:%d:%d: %v", name, p.line, p.col, err)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_importResources_Locations(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	tests := []struct {
+		name string
+		def  string
+		want string
+	}{
+		{
+			"winres.json",
+			`{
+  // Errors are collected
+  "RT_GROUP_ICON": {
+    "APP": {
+      "0000": [
+        "icon.png",
+        "icon16.png"
+      ]
+    }
+  },
+  "RT_GROUP_CURSOR": {
+    "#1": {"0000": [{"image": "cursor.png", "x": 1}]}
+  },
+  "RT_BITMAP": {
+    "BMP": {"zz": "image.bmp"}
+  }
+}`,
+			"%[1]s:6:9: [RT_GROUP_ICON][APP][0000][0] open %[2]s: no such file or directory\n" +
+				"%[1]s:12:21: [RT_GROUP_CURSOR][#1][0000][0] " + errInvalidCursor + "\n" +
+				`%[1]s:15:13: [RT_BITMAP][BMP][zz] invalid language identifier "zz"`,
+		},
+		{
+			"winres.yaml",
+			"RT_GROUP_ICON:\n  APP:\n    \"0000\":\n      - icon.png\n",
+			"%[1]s:4:9: [RT_GROUP_ICON][APP][0000][0] open %[2]s: no such file or directory",
+		},
+		{
+			"winres.toml",
+			"[RT_GROUP_ICON.APP]\n0000 = [\"icon.png\"]\n",
+			"[RT_GROUP_ICON][APP][0000][0] open %[2]s: no such file or directory",
+		},
+		{
+			"syntax.json",
+			"{\n  \"RT_RCDATA\": {\n    \"A\": {\"0000\": \"a.bin\"}\n  }\n  \"RT_HTML\": {}\n}",
+			"%[1]s:5:3: invalid character '\"' after object key:value pair",
+		},
+		{
+			"type.json",
+			"{\n  \"RT_RCDATA\": {\n    \"A\": []\n  }\n}",
+			"%[1]s:2:3: [RT_RCDATA] " + errInvalidSet,
+		},
+		{
+			"types.json",
+			"{\n  \"RT_RCDATA\": [],\n  \"RT_HTML\": 1,\n  \"RT_BITMAP\": {\"A\": {\"zz\": \"a.bmp\"}}\n}",
+			"%[1]s:2:3: [RT_RCDATA] " + errInvalidSet + "\n" +
+				"%[1]s:3:3: [RT_HTML] " + errInvalidSet + "\n" +
+				`%[1]s:4:23: [RT_BITMAP][A][zz] invalid language identifier "zz"`,
+		},
+		{
+			"items.json",
+			`{
+  "RT_ACCELERATOR": {
+    "KEYS": {"0409": [
+      {"key": "F99", "id": 1},
+      {"key": "A", "id": 2},
+      {"key": "B", "modifiers": "Meta", "id": 3}
+    ]}
+  },
+  "RT_DIALOG": {
+    "DLG": {"0409": {"controls": [{"class": "EDIT", "style": "DS_BLINK"}]}}
+  }
+}`,
+			"%[1]s:4:7: [RT_ACCELERATOR][KEYS][0409][0] " + errInvalidKey + `: "F99"` + "\n" +
+				"%[1]s:6:7: [RT_ACCELERATOR][KEYS][0409][2] " + errUnknownModifier + `: "Meta"` + "\n" +
+				"%[1]s:10:53: [RT_DIALOG][DLG][0409][controls][0][style] " + errUnknownStyle + `: "DS_BLINK"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(tmpDir, tt.name)
+			err := ioutil.WriteFile(name, []byte(tt.def), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := fmt.Sprintf(tt.want, name, filepath.Join(tmpDir, "icon.png"))
+			err = importResources(&winres.ResourceSet{}, name, importOptions{})
+			if err == nil || err.Error() != want {
+				t.Errorf("importResources() error =\n%v\nwant\n%s", err, want)
+			}
+		})
+	}
+}
+
+func Test_atPath(t *testing.T) {
+	err := atPath(errorList{errors.New("a"), atPath(errors.New("b"), "0")}, "RT_RCDATA", "X", "0409")
+	want := "[RT_RCDATA][X][0409] a\n[RT_RCDATA][X][0409][0] b"
+	if err.Error() != want {
+		t.Errorf("atPath() = %q, want %q", err, want)
+	}
+	if atPath(nil, "a") != nil {
+		t.Error("atPath(nil) should be nil")
+	}
+}
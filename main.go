@@ -1,15 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"image"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/tc-hib/winres"
 	"github.com/tc-hib/winres/version"
@@ -41,6 +43,8 @@ const (
 	flagInfoFilename    = "original-filename"
 
 	flagIconFile     = "icon"
+	flagIconSizes    = "icon-sizes"
+	flagIconFilter   = "icon-filter"
 	flagRequireAdmin = "admin"
 	flagManifest     = "manifest"
 
@@ -53,6 +57,22 @@ const (
 	authenticodeIgnore = "ignore"
 	authenticodeRemove = "remove"
 
+	flagFormat = "format"
+	flagTree   = "tree"
+	flagInline = "inline"
+	flagImages = "images"
+	flagStrict = "strict"
+
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatTOML = "toml"
+	formatRC   = "rc"
+	formatSyso = "syso"
+	formatRES  = "res"
+
+	imagesICO = "ico"
+	imagesPNG = "png"
+
 	gitTag = "git-tag"
 )
 
@@ -98,6 +118,13 @@ func main() {
 				Usage:     "Create an initial ./winres/winres.json",
 				Action:    cmdInit,
 				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  flagFormat,
+						Usage: "format of the resource definition: \"json\", \"yaml\" or \"toml\"",
+						Value: formatJSON,
+					},
+				},
 			},
 			{
 				Name:      "make",
@@ -107,10 +134,20 @@ func main() {
 				Flags: append([]cli.Flag{
 					&cli.StringFlag{
 						Name:      flagInput,
-						Usage:     "name of the input json file",
+						Usage:     "name of the input file (json, yaml, toml) or resource script (.rc)",
 						Value:     defaultJSONFile,
 						TakesFile: true,
 					},
+					&cli.StringFlag{
+						Name:  flagFormat,
+						Usage: "output format: \"syso\" (object files for go build) or \"res\" (for rc.exe, cvtres.exe or windres)",
+						Value: formatSyso,
+					},
+					&cli.BoolFlag{
+						Name:  flagStrict,
+						Usage: "reject comments and trailing commas in json files, misspelled types, and unknown manifest and version info keys",
+						Value: false,
+					},
 				},
 					commonMakeFlags...),
 			},
@@ -148,15 +185,24 @@ func main() {
 					},
 					&cli.StringFlag{
 						Name:      flagIconFile,
-						Usage:     "icon file (ico, png, ...)",
+						Usage:     "icon file (ico, svg, png, jpeg, gif, bmp, tiff, webp)",
 						Value:     defaultIconFile,
 						TakesFile: true,
 					},
+					&cli.IntSliceFlag{
+						Name:  flagIconSizes,
+						Usage: "sizes of the icon, such as 16,20,24,32,40,48,64,256 (default: 256,64,48,32,16)",
+					},
+					&cli.StringFlag{
+						Name:  flagIconFilter,
+						Usage: "resampling filter (nearest, bilinear, catmull-rom, mitchell, lanczos2, lanczos)",
+						Value: defaultResizeFilter,
+					},
 				}...),
 			},
 			{
 				Name:      "extract",
-				Usage:     "Extract all resources from an executable",
+				Usage:     "Extract all resources from an executable, an object file (syso) or a .res file",
 				Action:    cmdExtract,
 				ArgsUsage: "source_file.exe",
 				Flags: []cli.Flag{
@@ -170,8 +216,59 @@ func main() {
 						Usage: "extract the manifest as an xml file (not a json object)",
 						Value: false,
 					},
+					&cli.StringFlag{
+						Name:  flagFormat,
+						Usage: "format of the resource definition: \"json\" (winres.json), \"yaml\", \"toml\" or \"rc\" (winres.rc)",
+						Value: formatJSON,
+					},
+					&cli.BoolFlag{
+						Name:  flagTree,
+						Usage: "extract named RT_HTML and RT_RCDATA resources as a directory tree",
+						Value: false,
+					},
+					&cli.IntFlag{
+						Name:  flagInline,
+						Usage: "write RCDATA and custom resources up to this size (in bytes) in winres.json instead of files",
+						Value: 0,
+					},
+					&cli.StringFlag{
+						Name:  flagImages,
+						Usage: "extract icons and cursors as \"ico\" and \"cur\" files, or each of their images as a \"png\" file",
+						Value: imagesICO,
+					},
 				},
 			},
+			{
+				Name:      "validate",
+				Usage:     "Check a resource definition and report every problem, without making anything",
+				Action:    cmdValidate,
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:      flagInput,
+						Usage:     "name of the input file (json, yaml, toml) or resource script (.rc)",
+						Value:     defaultJSONFile,
+						TakesFile: true,
+					},
+					&cli.BoolFlag{
+						Name:  flagStrict,
+						Usage: "reject comments and trailing commas in json files, and fail on warnings (unknown keys are always reported)",
+						Value: false,
+					},
+				},
+			},
+			{
+				Name:      "schema",
+				Usage:     "Print a JSON Schema of winres.json",
+				Action:    cmdSchema,
+				ArgsUsage: " ",
+			},
+			{
+				Name:      "list",
+				Usage:     "List the resources of an executable, an object file (syso) or a .res file",
+				Action:    cmdList,
+				ArgsUsage: "source_file.exe",
+			},
 			{
 				Name:      "patch",
 				Usage:     "Replace resources in an executable file (exe, dll)",
@@ -180,9 +277,14 @@ func main() {
 				Flags: append([]cli.Flag{
 					&cli.StringFlag{
 						Name:  flagInput,
-						Usage: "name of the input json file",
+						Usage: "name of the input file (json, yaml, toml) or resource script (.rc)",
 						Value: defaultJSONFile,
 					},
+					&cli.BoolFlag{
+						Name:  flagStrict,
+						Usage: "reject comments and trailing commas in json files, misspelled types, and unknown manifest and version info keys",
+						Value: false,
+					},
 					&cli.BoolFlag{
 						Name:  flagDelete,
 						Usage: "delete all resources before adding the new ones",
@@ -208,12 +310,36 @@ func main() {
 	}
 }
 
-func cmdInit(_ *cli.Context) error {
-	err := os.MkdirAll(filepath.Dir(defaultJSONFile), 0755)
+func cmdInit(ctx *cli.Context) error {
+	format := ctx.String(flagFormat)
+	switch format {
+	case formatJSON, formatYAML, formatTOML:
+	default:
+		return errors.New("invalid format: " + format)
+	}
+	def := []byte(initJSON)
+	if format != formatJSON {
+		// Comments are lost in other formats
+		b, err := stripJSONC(def)
+		if err != nil {
+			return err
+		}
+		def, err = fromJSON(b, format)
+		if err != nil {
+			return err
+		}
+	}
+	name := filepath.Join(defaultOutDir, "winres."+format)
+
+	err := os.MkdirAll(defaultOutDir, 0755)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(name, def, 0644)
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(defaultJSONFile, []byte(initJSON), 0644)
+	err = ioutil.WriteFile(filepath.Join(defaultOutDir, schemaFile), []byte(schemaJSON), 0644)
 	if err != nil {
 		return err
 	}
@@ -226,19 +352,30 @@ func cmdInit(_ *cli.Context) error {
 		return err
 	}
 
-	fmt.Println("Created", defaultJSONFile)
+	fmt.Println("Created", name)
 
 	return nil
 }
 
 func cmdMake(ctx *cli.Context) error {
-	targets, err := getTargets(ctx)
-	if err != nil {
-		return err
+	var (
+		targets []target
+		err     error
+	)
+
+	switch ctx.String(flagFormat) {
+	case formatSyso:
+		targets, err = getTargets(ctx)
+		if err != nil {
+			return err
+		}
+	case formatRES:
+	default:
+		return errors.New("invalid format: " + ctx.String(flagFormat))
 	}
 
 	rs := &winres.ResourceSet{}
-	err = importResources(rs, ctx.String(flagInput))
+	err = importResources(rs, inputFile(ctx), importOptions{strict: ctx.Bool(flagStrict)})
 	if err != nil {
 		return err
 	}
@@ -248,6 +385,14 @@ func cmdMake(ctx *cli.Context) error {
 		return err
 	}
 
+	if ctx.String(flagFormat) == formatRES {
+		name := ctx.String(flagOutput)
+		if !ctx.Bool(flagNoSuffix) {
+			name += ".res"
+		}
+		return writeRESFile(rs, name)
+	}
+
 	for _, t := range targets {
 		err = writeObjectFile(rs, t.name, t.arch)
 		if err != nil {
@@ -296,26 +441,85 @@ func cmdExtract(ctx *cli.Context) error {
 		cli.ShowSubcommandHelpAndExit(ctx, 1)
 	}
 
-	f, err := os.Open(ctx.Args().Get(0))
+	format := ctx.String(flagFormat)
+	switch format {
+	case formatJSON, formatYAML, formatTOML, formatRC:
+	default:
+		return errors.New("invalid format: " + format)
+	}
+
+	images := ctx.String(flagImages)
+	switch images {
+	case imagesICO:
+	case imagesPNG:
+		if format == formatRC {
+			return errors.New("png images can't be used in a resource script")
+		}
+	default:
+		return errors.New("invalid image format: " + images)
+	}
+
+	rs, err := readResourceSet(ctx.Args().Get(0))
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	rs, err := winres.LoadFromEXE(f)
+	out := ctx.String(flagOutputDir)
+	err = os.MkdirAll(out, 0755)
 	if err != nil {
 		return err
 	}
 
-	f.Close()
+	exportResources(out, rs, exportOptions{
+		manifestInJSON: !ctx.Bool(flagXMLManifest),
+		format:         format,
+		tree:           ctx.Bool(flagTree),
+		inline:         ctx.Int(flagInline),
+		pngImages:      images == imagesPNG,
+	})
 
-	out := ctx.String(flagOutputDir)
-	err = os.MkdirAll(out, 0755)
+	return nil
+}
+
+func cmdValidate(ctx *cli.Context) error {
+	name := inputFile(ctx)
+	problems, warnings := validateResources(name, importOptions{strict: ctx.Bool(flagStrict)})
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: %d problem(s) found", name, len(problems))
+	}
+	if len(warnings) > 0 && ctx.Bool(flagStrict) {
+		return fmt.Errorf("%s: %d warning(s) found", name, len(warnings))
+	}
+	if len(warnings) > 0 {
+		fmt.Printf("%s is valid, with %d warning(s)\n", name, len(warnings))
+		return nil
+	}
+	fmt.Println(name, "is valid")
+	return nil
+}
+
+func cmdSchema(_ *cli.Context) error {
+	fmt.Print(schemaJSON)
+	return nil
+}
+
+func cmdList(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	}
+
+	rs, err := readResourceSet(ctx.Args().Get(0))
 	if err != nil {
 		return err
 	}
 
-	exportResources(out, rs, !ctx.Bool(flagXMLManifest))
+	listResources(os.Stdout, rs)
 
 	return nil
 }
@@ -358,7 +562,7 @@ You might want to use --%[1]s=%[2]s or --%[1]s=%[3]s to either remove or ignore
 		}
 	}
 
-	err = importResources(rs, ctx.String(flagInput))
+	err = importResources(rs, inputFile(ctx), importOptions{strict: ctx.Bool(flagStrict)})
 	if err != nil {
 		return err
 	}
@@ -407,6 +611,25 @@ You might want to use --%[1]s=%[2]s or --%[1]s=%[3]s to either remove or ignore
 	return os.Rename(exe+".tmp", exe)
 }
 
+// inputFile returns the name of the resource definition.
+// When it is not specified, and winres.json does not exist, a yaml or toml file is searched instead.
+func inputFile(ctx *cli.Context) string {
+	name := ctx.String(flagInput)
+	if ctx.IsSet(flagInput) {
+		return name
+	}
+	if _, err := os.Stat(name); err == nil {
+		return name
+	}
+	for _, ext := range []string{".yaml", ".yml", ".toml"} {
+		alt := strings.TrimSuffix(name, filepath.Ext(name)) + ext
+		if _, err := os.Stat(alt); err == nil {
+			return alt
+		}
+	}
+	return name
+}
+
 func getTargets(ctx *cli.Context) ([]target, error) {
 	var (
 		ns   = ctx.Bool(flagNoSuffix)
@@ -597,36 +820,71 @@ func getGitTag() (string, error) {
 
 func simplySetIcon(rs *winres.ResourceSet, ctx *cli.Context) error {
 	name := ctx.String(flagIconFile)
-	f, err := os.Open(name)
-	if err != nil {
+	if _, err := os.Stat(name); err != nil {
 		if !os.IsNotExist(err) {
 			return err
 		}
 		log.Println("did not find icon ", name)
 		return nil
 	}
-	defer f.Close()
 
-	var icon *winres.Icon
-	if strings.ToLower(filepath.Ext(name)) == ".ico" {
-		icon, err = winres.LoadICO(f)
-		if err != nil {
-			return err
-		}
-	} else {
-		img, _, err := image.Decode(f)
-		if err != nil {
-			return err
-		}
-		icon, err = winres.NewIconFromResizedImage(img, nil)
-		if err != nil {
-			return err
-		}
+	icon, err := makeIcon(name, iconOptions{
+		sizes:  ctx.IntSlice(flagIconSizes),
+		filter: ctx.String(flagIconFilter),
+	})
+	if err != nil {
+		return err
 	}
 
 	return rs.SetIcon(winres.ID(1), icon)
 }
 
+// readResourceSet reads resources from an executable, a .res file, or a COFF object such as a .syso file.
+func readResourceSet(name string) (*winres.ResourceSet, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if isRES(b) {
+		rs := &winres.ResourceSet{}
+		return rs, readRES(rs, b)
+	}
+	if isCOFF(b) {
+		rs := &winres.ResourceSet{}
+		return rs, readCOFF(rs, b)
+	}
+
+	return winres.LoadFromEXE(bytes.NewReader(b))
+}
+
+// listResources writes a table of resources with their size.
+func listResources(w io.Writer, rs *winres.ResourceSet) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tNAME\tLANG\tSIZE")
+	rs.Walk(func(typeID, resID winres.Identifier, langID uint16, data []byte) bool {
+		t, r, l := idsToStrings(typeID, resID, langID)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", t, r, l, len(data))
+		return true
+	})
+	tw.Flush()
+}
+
+func writeRESFile(rs *winres.ResourceSet, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = writeRES(f, rs)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
 func writeObjectFile(rs *winres.ResourceSet, name string, arch winres.Arch) error {
 	f, err := os.Create(name)
 	if err != nil {
@@ -644,35 +902,57 @@ func writeObjectFile(rs *winres.ResourceSet, name string, arch winres.Arch) erro
 
 // language=json
 const initJSON = `{
+  // JSON Schema, for completion and validation in editors
+  "$schema": "winres.schema.json",
+  // Icon of the executable, resized to 256, 64, 48, 32 and 16 pixels
   "RT_GROUP_ICON": {
     "APP": {
-      "0000": [
-        "icon.png",
-        "icon16.png"
-      ]
+      "0000": {
+        "image": "icon.png",
+        // Simpler images for small sizes
+        "overrides": {
+          "16": "icon16.png",
+          "32": "icon16.png"
+        }
+      }
     }
   },
   "RT_MANIFEST": {
     "#1": {
       "0409": {
+        // Identity of a side-by-side assembly, better omitted in a plain application
         "identity": {
           "name": "",
           "version": ""
         },
         "description": "",
+        // "vista", "win7", "win8", "win8.1" or "win10"
         "minimum-os": "win7",
+        // "as invoker", "highest" (highest level available to the user) or "administrator"
         "execution-level": "as invoker",
+        // Allow UI automation to drive windows of other processes (requires a signed executable)
         "ui-access": false,
+        // Elevate without prompting (only for Windows components)
         "auto-elevate": false,
+        // "unaware", "system", "per monitor" or "per monitor v2" (recommended)
         "dpi-awareness": "system",
+        // Disable visual styles of windows and controls
         "disable-theming": false,
+        // Let EnumWindows see immersive windows too
         "disable-window-filtering": false,
+        // Smooth scrolling with high resolution devices
         "high-resolution-scrolling-aware": false,
+        // Even finer scrolling with ultra high resolution devices
         "ultra-high-resolution-scrolling-aware": false,
+        // Allow paths longer than MAX_PATH (Windows 10, requires a registry setting)
         "long-path-aware": false,
+        // Run printer drivers in a separate process
         "printer-driver-isolation": false,
+        // Let GDI scale unaware content on high DPI screens
         "gdi-scaling": false,
+        // Use the segment heap instead of the NT heap (Windows 10 2004)
         "segment-heap": false,
+        // Use version 6 of comctl32.dll, needed for visual styles
         "use-common-controls-v6": false
       }
     }
@@ -681,22 +961,36 @@ const initJSON = `{
     "#1": {
       "0000": {
         "fixed": {
+          // Binary versions, as four numbers, also set by --file-version and --product-version
           "file_version": "0.0.0.0",
           "product_version": "0.0.0.0"
         },
+        // File properties, by language
         "info": {
           "0409": {
+            // Any additional information
             "Comments": "",
+            // Name of the company that produced the file
             "CompanyName": "",
+            // Description shown by the task manager
             "FileDescription": "",
+            // Version shown in file properties, which can be any text
             "FileVersion": "",
+            // Internal name of the file, usually the original file name without its extension
             "InternalName": "",
+            // Copyright notice
             "LegalCopyright": "",
+            // Trademarks that apply to the file
             "LegalTrademarks": "",
+            // Original name of the file, to know if it has been renamed
             "OriginalFilename": "",
+            // Describes a private build, when the PrivateBuild flag is set
             "PrivateBuild": "",
+            // Name of the product the file is distributed with
             "ProductName": "",
+            // Version of the product, which can be any text
             "ProductVersion": "",
+            // Describes a special build, when the SpecialBuild flag is set
             "SpecialBuild": ""
           }
         }
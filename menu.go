@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	errInvalidMenu    = "invalid menu"
+	errEmptyPopup     = "a popup menu needs at least one item"
+	errUnknownFlag    = "unknown flag"
+	errUnterminatedSZ = "unterminated string"
+)
+
+// menuItem is the JSON form of a menu item.
+//
+// An item that has sub-items is a popup menu.
+// An empty list of sub-items is an error, because a compiled popup menu can't be empty.
+type menuItem struct {
+	Text      string     `json:"text,omitempty"`
+	ID        uint32     `json:"id,omitempty"`
+	Separator bool       `json:"separator,omitempty"`
+	Flags     string     `json:"flags,omitempty"`
+	HelpID    uint32     `json:"help_id,omitempty"`
+	Items     []menuItem `json:"items,omitempty"`
+}
+
+// MENUEX item types and states
+// https://docs.microsoft.com/en-us/windows/win32/menurc/menuex-template-item
+const (
+	mftBitmap       = 0x0004
+	mftMenuBarBreak = 0x0020
+	mftMenuBreak    = 0x0040
+	mftOwnerDraw    = 0x0100
+	mftRadioCheck   = 0x0200
+	mftSeparator    = 0x0800
+	mftRightOrder   = 0x2000
+	mftRightJustify = 0x4000
+
+	mfsGrayed  = 0x0003
+	mfsChecked = 0x0008
+	mfsHilite  = 0x0080
+	mfsDefault = 0x1000
+
+	menuPopup = 0x01
+	menuLast  = 0x80
+)
+
+// Options of the old MENU format
+// https://docs.microsoft.com/en-us/windows/win32/menurc/normalmenuitem
+const (
+	mfGrayed       = 0x0001
+	mfDisabled     = 0x0002
+	mfChecked      = 0x0008
+	mfPopup        = 0x0010
+	mfMenuBarBreak = 0x0020
+	mfMenuBreak    = 0x0040
+	mfEnd          = 0x0080
+	mfOwnerDraw    = 0x0100
+	mfSeparator    = 0x0800
+	mfHelp         = 0x4000
+)
+
+var menuTypeFlags = []struct {
+	name string
+	flag uint32
+}{
+	{"bitmap", mftBitmap},
+	{"menubarbreak", mftMenuBarBreak},
+	{"menubreak", mftMenuBreak},
+	{"ownerdraw", mftOwnerDraw},
+	{"radiocheck", mftRadioCheck},
+	{"rightorder", mftRightOrder},
+	{"rightjustify", mftRightJustify},
+}
+
+var menuStateFlags = []struct {
+	name string
+	flag uint32
+}{
+	{"grayed", mfsGrayed},
+	{"checked", mfsChecked},
+	{"hilite", mfsHilite},
+	{"default", mfsDefault},
+}
+
+// loadMenu makes a MENUEX resource from its JSON definition, or loads a binary file.
+func loadMenu(dir string, x interface{}) ([]byte, error) {
+	var items []menuItem
+	switch x := x.(type) {
+	case string:
+		return ioutil.ReadFile(filepath.Join(dir, x))
+	case []menuItem:
+		// Menu read from a resource script
+		items = x
+	case []interface{}:
+		j, _ := json.Marshal(x)
+		err := json.Unmarshal(j, &items)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New(errInvalidMenu)
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New(errInvalidMenu)
+	}
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, struct {
+		Version uint16
+		Offset  uint16
+		HelpID  uint32
+	}{1, 4, 0})
+
+	err := writeMenuItems(buf, items)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeMenuItems(buf *bytes.Buffer, items []menuItem) error {
+	for i, item := range items {
+		typ, state, err := parseMenuFlags(item.Flags)
+		if err != nil {
+			return fmt.Errorf("%q: %v", item.Text, err)
+		}
+		// The key is present, but the list is empty
+		if item.Items != nil && len(item.Items) == 0 {
+			return fmt.Errorf("%q: %s", item.Text, errEmptyPopup)
+		}
+		if item.Separator {
+			typ |= mftSeparator
+		}
+
+		var flags uint16
+		if len(item.Items) > 0 {
+			flags |= menuPopup
+		}
+		if i == len(items)-1 {
+			flags |= menuLast
+		}
+
+		binary.Write(buf, binary.LittleEndian, struct {
+			Type  uint32
+			State uint32
+			ID    uint32
+			Flags uint16
+		}{typ, state, item.ID, flags})
+		writeSZ(buf, item.Text)
+		padDWORD(buf)
+
+		if flags&menuPopup != 0 {
+			binary.Write(buf, binary.LittleEndian, item.HelpID)
+			err = writeMenuItems(buf, item.Items)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseMenuFlags(s string) (typ uint32, state uint32, err error) {
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" {
+			continue
+		}
+		found := false
+		for _, t := range menuTypeFlags {
+			if t.name == f {
+				typ |= t.flag
+				found = true
+			}
+		}
+		for _, st := range menuStateFlags {
+			if st.name == f || f == "disabled" && st.flag == mfsGrayed {
+				state |= st.flag
+				found = true
+			}
+		}
+		if !found {
+			return 0, 0, fmt.Errorf("%s: %q", errUnknownFlag, f)
+		}
+	}
+	return typ, state, nil
+}
+
+func menuFlagsString(typ uint32, state uint32) string {
+	var flags []string
+	for _, t := range menuTypeFlags {
+		if typ&t.flag == t.flag {
+			flags = append(flags, t.name)
+		}
+	}
+	for _, st := range menuStateFlags {
+		if state&st.flag != 0 {
+			flags = append(flags, st.name)
+		}
+	}
+	return strings.Join(flags, ",")
+}
+
+// readMenu decodes a MENU or a MENUEX resource.
+func readMenu(data []byte) ([]menuItem, error) {
+	if len(data) < 4 {
+		return nil, errors.New(errInvalidMenu)
+	}
+
+	version := binary.LittleEndian.Uint16(data)
+	offset := int(binary.LittleEndian.Uint16(data[2:]))
+
+	pos := 4 + offset
+	switch version {
+	case 0:
+		return readMenuItems(data, &pos)
+	case 1:
+		return readMenuExItems(data, &pos)
+	}
+
+	return nil, errors.New(errInvalidMenu)
+}
+
+func readMenuItems(data []byte, pos *int) ([]menuItem, error) {
+	var items []menuItem
+	for {
+		if *pos+2 > len(data) {
+			return nil, errors.New(errInvalidMenu)
+		}
+		opt := binary.LittleEndian.Uint16(data[*pos:])
+		*pos += 2
+
+		item := menuItem{}
+		if opt&mfPopup == 0 {
+			if *pos+2 > len(data) {
+				return nil, errors.New(errInvalidMenu)
+			}
+			item.ID = uint32(binary.LittleEndian.Uint16(data[*pos:]))
+			*pos += 2
+		}
+
+		var err error
+		item.Text, err = readSZ(data, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		var typ, state uint32
+		if opt&(mfGrayed|mfDisabled) != 0 {
+			state |= mfsGrayed
+		}
+		if opt&mfChecked != 0 {
+			state |= mfsChecked
+		}
+		if opt&mfMenuBarBreak != 0 {
+			typ |= mftMenuBarBreak
+		}
+		if opt&mfMenuBreak != 0 {
+			typ |= mftMenuBreak
+		}
+		if opt&mfOwnerDraw != 0 {
+			typ |= mftOwnerDraw
+		}
+		if opt&mfHelp != 0 {
+			typ |= mftRightJustify
+		}
+		item.Flags = menuFlagsString(typ, state)
+		item.Separator = opt&mfSeparator != 0 || opt&mfPopup == 0 && item.ID == 0 && item.Text == ""
+
+		if opt&mfPopup != 0 {
+			item.Items, err = readMenuItems(data, pos)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		items = append(items, item)
+		if opt&mfEnd != 0 {
+			return items, nil
+		}
+	}
+}
+
+func readMenuExItems(data []byte, pos *int) ([]menuItem, error) {
+	var items []menuItem
+	for {
+		*pos = (*pos + 3) &^ 3
+		if *pos+14 > len(data) {
+			return nil, errors.New(errInvalidMenu)
+		}
+		typ := binary.LittleEndian.Uint32(data[*pos:])
+		state := binary.LittleEndian.Uint32(data[*pos+4:])
+		item := menuItem{
+			ID:        binary.LittleEndian.Uint32(data[*pos+8:]),
+			Separator: typ&mftSeparator != 0,
+			Flags:     menuFlagsString(typ, state),
+		}
+		flags := binary.LittleEndian.Uint16(data[*pos+12:])
+		*pos += 14
+
+		var err error
+		item.Text, err = readSZ(data, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		if flags&menuPopup != 0 {
+			*pos = (*pos + 3) &^ 3
+			if *pos+4 > len(data) {
+				return nil, errors.New(errInvalidMenu)
+			}
+			item.HelpID = binary.LittleEndian.Uint32(data[*pos:])
+			*pos += 4
+			item.Items, err = readMenuExItems(data, pos)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		items = append(items, item)
+		if flags&menuLast != 0 {
+			return items, nil
+		}
+	}
+}
+
+// writeSZ writes a NUL terminated UTF-16 string.
+func writeSZ(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.LittleEndian, utf16.Encode([]rune(s)))
+	buf.Write([]byte{0, 0})
+}
+
+// readSZ reads a NUL terminated UTF-16 string.
+func readSZ(data []byte, pos *int) (string, error) {
+	var u []uint16
+	for {
+		if *pos+2 > len(data) {
+			return "", errors.New(errUnterminatedSZ)
+		}
+		c := binary.LittleEndian.Uint16(data[*pos:])
+		*pos += 2
+		if c == 0 {
+			return string(utf16.Decode(u)), nil
+		}
+		u = append(u, c)
+	}
+}
+
+func padDWORD(buf *bytes.Buffer) {
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+}
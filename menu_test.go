@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_loadMenu(t *testing.T) {
+	var x interface{}
+	err := json.Unmarshal([]byte(`[
+		{"text": "&File", "items": [
+			{"text": "&Open", "id": 100},
+			{"separator": true},
+			{"text": "Recent", "help_id": 42, "items": [
+				{"text": "None", "id": 101, "flags": "grayed"}
+			]},
+			{"text": "E&xit", "id": 102, "flags": "Default"}
+		]},
+		{"text": "&Help", "id": 103, "flags": "rightjustify,checked,radiocheck"}
+	]`), &x)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := loadMenu("", x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 1 || data[2] != 4 || len(data)%4 != 0 {
+		t.Errorf("bad MENUEX header or alignment: %v", data[:8])
+	}
+
+	items, err := readMenu(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []menuItem{
+		{Text: "&File", Items: []menuItem{
+			{Text: "&Open", ID: 100},
+			{Separator: true},
+			{Text: "Recent", HelpID: 42, Items: []menuItem{
+				{Text: "None", ID: 101, Flags: "grayed"},
+			}},
+			{Text: "E&xit", ID: 102, Flags: "default"},
+		}},
+		{Text: "&Help", ID: 103, Flags: "radiocheck,rightjustify,checked"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("readMenu() = %+v, want %+v", items, want)
+	}
+}
+
+func Test_loadMenu_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		x    interface{}
+	}{
+		{"object", map[string]interface{}{}},
+		{"empty", []interface{}{}},
+		{"flag", []interface{}{map[string]interface{}{"text": "A", "flags": "blinking"}}},
+		{"sub flag", []interface{}{map[string]interface{}{"text": "A", "items": []interface{}{map[string]interface{}{"flags": "x"}}}}},
+		{"empty popup", []interface{}{map[string]interface{}{"text": "A", "items": []interface{}{}}}},
+		{"type", []interface{}{"A"}},
+		{"file", "missing.bin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadMenu("_testdata", tt.x); err == nil {
+				t.Error("loadMenu() should fail")
+			}
+		})
+	}
+}
+
+func Test_readMenu(t *testing.T) {
+	// Old MENU format, as compiled by rc.exe:
+	//   POPUP "&File" { MENUITEM "&Open", 100, CHECKED; MENUITEM SEPARATOR; MENUITEM "&Quit", 101, GRAYED }
+	//   MENUITEM "&Help", 102, HELP
+	data := []byte{
+		0, 0, 0, 0,
+		0x10, 0, '&', 0, 'F', 0, 'i', 0, 'l', 0, 'e', 0, 0, 0,
+		0x08, 0, 100, 0, '&', 0, 'O', 0, 'p', 0, 'e', 0, 'n', 0, 0, 0,
+		0x00, 0, 0, 0, 0, 0,
+		0x81, 0, 101, 0, '&', 0, 'Q', 0, 'u', 0, 'i', 0, 't', 0, 0, 0,
+		0x80, 0x40, 102, 0, '&', 0, 'H', 0, 'e', 0, 'l', 0, 'p', 0, 0, 0,
+	}
+	items, err := readMenu(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []menuItem{
+		{Text: "&File", Items: []menuItem{
+			{Text: "&Open", ID: 100, Flags: "checked"},
+			{Separator: true},
+			{Text: "&Quit", ID: 101, Flags: "grayed"},
+		}},
+		{Text: "&Help", ID: 102, Flags: "rightjustify"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("readMenu() = %+v, want %+v", items, want)
+	}
+
+	for i := 0; i < len(data)-1; i++ {
+		if _, err := readMenu(data[:i]); err == nil {
+			t.Errorf("readMenu() should fail on truncated data (%d bytes)", i)
+		}
+	}
+	if _, err := readMenu([]byte{2, 0, 0, 0, 0, 0}); err == nil {
+		t.Error("readMenu() should fail on unknown versions")
+	}
+}
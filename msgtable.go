@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errInvalidMessageTable = "invalid message table"
+	errUnknownSeverity     = "unknown severity"
+	errFacilityTooBig      = "facility must be lower than 0x1000"
+)
+
+// message is the JSON form of a message table entry.
+//
+// The actual message identifier is made of the severity, the customer bit, the facility and the ID:
+// https://docs.microsoft.com/en-us/windows/win32/eventlog/event-identifiers
+type message struct {
+	ID       uint16 `json:"id"`
+	Severity string `json:"severity,omitempty"`
+	Facility uint16 `json:"facility,omitempty"`
+	Customer bool   `json:"customer,omitempty"`
+	Text     string `json:"text"`
+}
+
+var severityNames = []string{"success", "informational", "warning", "error"}
+
+func (m message) fullID() (uint32, error) {
+	sev := -1
+	for i, s := range severityNames {
+		if strings.EqualFold(m.Severity, s) {
+			sev = i
+		}
+	}
+	if m.Severity == "" {
+		sev = 0
+	}
+	if sev < 0 {
+		return 0, fmt.Errorf("%s: %q", errUnknownSeverity, m.Severity)
+	}
+	if m.Facility > 0xFFF {
+		return 0, errors.New(errFacilityTooBig)
+	}
+
+	id := uint32(sev)<<30 | uint32(m.Facility)<<16 | uint32(m.ID)
+	if m.Customer {
+		id |= 1 << 29
+	}
+	return id, nil
+}
+
+func messageFromID(id uint32, text string) message {
+	m := message{
+		ID:       uint16(id),
+		Facility: uint16(id>>16) & 0xFFF,
+		Customer: id&(1<<29) != 0,
+		Text:     text,
+	}
+	if sev := id >> 30; sev != 0 {
+		m.Severity = severityNames[sev]
+	}
+	return m
+}
+
+// importMessageTable adds a message table to the resource set.
+//
+// The definition can be a list of messages, a message compiler source (.mc), or a binary file.
+//
+// A .mc file declares its own languages.
+// They are all imported when the language is neutral (0000), otherwise only the given language is.
+func importMessageTable(rs *winres.ResourceSet, dir string, resID winres.Identifier, langID uint16, x interface{}) error {
+	switch x := x.(type) {
+	case string:
+		b, err := ioutil.ReadFile(filepath.Join(dir, x))
+		if err != nil {
+			return err
+		}
+		if strings.ToLower(filepath.Ext(x)) != ".mc" {
+			return rs.Set(winres.RT_MESSAGETABLE, resID, langID, b)
+		}
+		tables, err := parseMC(b)
+		if err != nil {
+			return fmt.Errorf("%s: %v", x, err)
+		}
+		if langID != winres.LCIDNeutral {
+			t, ok := tables[langID]
+			if !ok {
+				return fmt.Errorf("%s: language %04X not found", x, langID)
+			}
+			tables = map[uint16]map[uint32]string{langID: t}
+		}
+		for l, t := range tables {
+			err = rs.Set(winres.RT_MESSAGETABLE, resID, l, makeMessageTable(t))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case []interface{}:
+		var messages []message
+		j, _ := json.Marshal(x)
+		err := json.Unmarshal(j, &messages)
+		if err != nil {
+			return err
+		}
+		t := make(map[uint32]string, len(messages))
+		for _, m := range messages {
+			id, err := m.fullID()
+			if err != nil {
+				return err
+			}
+			if _, ok := t[id]; ok {
+				return fmt.Errorf("duplicate message id 0x%08X", id)
+			}
+			t[id] = m.Text
+		}
+		return rs.Set(winres.RT_MESSAGETABLE, resID, langID, makeMessageTable(t))
+	}
+
+	return errors.New(errInvalidMessageTable)
+}
+
+// makeMessageTable returns the binary form of a message table.
+//
+// Messages are grouped into blocks of contiguous IDs.
+// Texts are always stored as UTF-16 strings.
+//
+// https://docs.microsoft.com/en-us/windows/win32/api/winnt/ns-winnt-message_resource_data
+func makeMessageTable(t map[uint32]string) []byte {
+	ids := make([]uint32, 0, len(t))
+	for id := range t {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	type block struct {
+		low, high uint32
+		offset    uint32
+	}
+	var blocks []block
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1]+1 {
+			blocks = append(blocks, block{low: id})
+		}
+		blocks[len(blocks)-1].high = id
+	}
+
+	entries := &bytes.Buffer{}
+	offset := uint32(4 + 12*len(blocks))
+	b := 0
+	for _, id := range ids {
+		if id == blocks[b].low {
+			blocks[b].offset = offset + uint32(entries.Len())
+		}
+		u := utf16.Encode([]rune(t[id]))
+		length := (4 + len(u)*2 + 2 + 3) &^ 3
+		binary.Write(entries, binary.LittleEndian, uint16(length))
+		binary.Write(entries, binary.LittleEndian, uint16(1))
+		binary.Write(entries, binary.LittleEndian, u)
+		entries.Write(make([]byte, length-4-len(u)*2))
+		if id == blocks[b].high {
+			b++
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint32(len(blocks)))
+	for _, blk := range blocks {
+		binary.Write(buf, binary.LittleEndian, [3]uint32{blk.low, blk.high, blk.offset})
+	}
+	buf.Write(entries.Bytes())
+
+	return buf.Bytes()
+}
+
+// readMessageTable decodes a binary message table.
+func readMessageTable(data []byte) ([]message, error) {
+	if len(data) < 4 {
+		return nil, errors.New(errInvalidMessageTable)
+	}
+	n := binary.LittleEndian.Uint32(data)
+	if uint64(n)*12+4 > uint64(len(data)) {
+		return nil, errors.New(errInvalidMessageTable)
+	}
+
+	var messages []message
+	for i := 0; i < int(n); i++ {
+		low := binary.LittleEndian.Uint32(data[4+i*12:])
+		high := binary.LittleEndian.Uint32(data[8+i*12:])
+		pos := int(binary.LittleEndian.Uint32(data[12+i*12:]))
+		if high < low || high-low > 0xFFFF {
+			return nil, errors.New(errInvalidMessageTable)
+		}
+		for id := uint64(low); id <= uint64(high); id++ {
+			if pos < 0 || pos+4 > len(data) {
+				return nil, errors.New(errInvalidMessageTable)
+			}
+			length := int(binary.LittleEndian.Uint16(data[pos:]))
+			flags := binary.LittleEndian.Uint16(data[pos+2:])
+			if length < 4 || pos+length > len(data) {
+				return nil, errors.New(errInvalidMessageTable)
+			}
+			text := data[pos+4 : pos+length]
+			var s string
+			if flags&1 != 0 {
+				u := make([]uint16, len(text)/2)
+				for j := range u {
+					u[j] = binary.LittleEndian.Uint16(text[j*2:])
+				}
+				s = string(utf16.Decode(u))
+			} else {
+				r := make([]rune, len(text))
+				for j := range text {
+					r[j] = rune(text[j])
+				}
+				s = string(r)
+			}
+			messages = append(messages, messageFromID(uint32(id), strings.TrimRight(s, "\x00")))
+			pos += length
+		}
+	}
+
+	return messages, nil
+}
+
+// parseMC compiles a message text file, the input format of Microsoft's message compiler.
+//
+// It returns message texts indexed by language, then by full message ID.
+//
+// https://docs.microsoft.com/en-us/windows/win32/eventlog/message-text-files
+func parseMC(b []byte) (map[uint16]map[uint32]string, error) {
+	var (
+		severities = map[string]uint32{"success": 0, "informational": 1, "warning": 2, "error": 3}
+		facilities = map[string]uint32{"system": 0xFF, "application": 0xFFF}
+		languages  = map[string]uint16{"english": 0x409}
+		lastIDs    = map[uint32]uint32{}
+		tables     = map[uint16]map[uint32]string{}
+
+		id       uint32
+		severity uint32
+		facility uint32
+		inMsg    bool
+	)
+
+	s := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(b, []byte("\xEF\xBB\xBF"))))
+	line := 0
+	next := func() (string, bool) {
+		if !s.Scan() {
+			return "", false
+		}
+		line++
+		return strings.TrimRight(s.Text(), "\r"), true
+	}
+	fail := func(format string, a ...interface{}) error {
+		return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, a...))
+	}
+
+	for {
+		text, ok := next()
+		if !ok {
+			break
+		}
+		text = strings.TrimSpace(text)
+		if text == "" || text[0] == ';' {
+			continue
+		}
+
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return nil, fail("syntax error")
+		}
+		key := strings.ToLower(strings.TrimSpace(text[:eq]))
+		value := strings.TrimSpace(text[eq+1:])
+
+		switch key {
+		case "severitynames", "facilitynames", "languagenames":
+			// Lists look like (Name=Value:Symbol ...) and may span several lines
+			for !strings.Contains(value, ")") {
+				more, ok := next()
+				if !ok {
+					return nil, fail("unterminated %s", text[:eq])
+				}
+				value += " " + more
+			}
+			value = strings.TrimSpace(value)
+			if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
+				return nil, fail("syntax error in %s", text[:eq])
+			}
+			defs, err := parseMCNames(value[1 : len(value)-1])
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			for name, v := range defs {
+				switch key {
+				case "severitynames":
+					if v > 3 {
+						return nil, fail("invalid severity value for %s", name)
+					}
+					severities[name] = v
+				case "facilitynames":
+					if v > 0xFFF {
+						return nil, fail("invalid facility value for %s", name)
+					}
+					facilities[name] = v
+				case "languagenames":
+					if v > 0xFFFF {
+						return nil, fail("invalid language value for %s", name)
+					}
+					languages[name] = uint16(v)
+				}
+			}
+
+		case "messageidtypedef", "messageidtypedefmacro", "outputbase", "symbolicname":
+
+		case "messageid":
+			inMsg = true
+			switch {
+			case value == "":
+				id = lastIDs[facility] + 1
+			case value[0] == '+':
+				n, err := parseMCNumber(value[1:])
+				if err != nil {
+					return nil, fail("invalid message id %q", value)
+				}
+				id = lastIDs[facility] + n
+			default:
+				n, err := parseMCNumber(value)
+				if err != nil {
+					return nil, fail("invalid message id %q", value)
+				}
+				id = n
+			}
+			if id > 0xFFFF {
+				return nil, fail("message id too big: %s", value)
+			}
+
+		case "severity":
+			v, ok := severities[strings.ToLower(value)]
+			if !ok {
+				return nil, fail("unknown severity %q", value)
+			}
+			severity = v
+
+		case "facility":
+			v, ok := facilities[strings.ToLower(value)]
+			if !ok {
+				return nil, fail("unknown facility %q", value)
+			}
+			facility = v
+
+		case "language":
+			if !inMsg {
+				return nil, fail("Language found before MessageId")
+			}
+			langID, ok := languages[strings.ToLower(value)]
+			if !ok {
+				return nil, fail("unknown language %q", value)
+			}
+			msg := &strings.Builder{}
+			for {
+				t, ok := next()
+				if !ok {
+					return nil, fail("unterminated message text")
+				}
+				if t == "." {
+					break
+				}
+				// Like mc.exe, each line ends with CRLF, unless it ends with %0
+				if strings.HasSuffix(t, "%0") {
+					msg.WriteString(strings.TrimSuffix(t, "%0"))
+				} else {
+					msg.WriteString(t)
+					msg.WriteString("\r\n")
+				}
+			}
+			if tables[langID] == nil {
+				tables[langID] = make(map[uint32]string)
+			}
+			tables[langID][severity<<30|facility<<16|id] = msg.String()
+			lastIDs[facility] = id
+
+		default:
+			return nil, fail("unknown keyword %q", text[:eq])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(tables) == 0 {
+		return nil, errors.New("no message found")
+	}
+
+	return tables, nil
+}
+
+// Spaces around separators are allowed in name lists
+var mcSeparators = regexp.MustCompile(`\s*([=:])\s*`)
+
+// parseMCNames parses the content of a SeverityNames, FacilityNames or LanguageNames list.
+//
+// Names are returned in lower case, because mc.exe keywords are not case sensitive.
+func parseMCNames(list string) (map[string]uint32, error) {
+	defs := make(map[string]uint32)
+	for _, def := range strings.Fields(mcSeparators.ReplaceAllString(list, "$1")) {
+		eq := strings.IndexByte(def, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("syntax error in %q", def)
+		}
+		v := def[eq+1:]
+		if colon := strings.IndexByte(v, ':'); colon >= 0 {
+			v = v[:colon]
+		}
+		n, err := parseMCNumber(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in %q", def)
+		}
+		defs[strings.ToLower(def[:eq])] = n
+	}
+	return defs, nil
+}
+
+func parseMCNumber(s string) (uint32, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 0, 32)
+	return uint32(n), err
+}
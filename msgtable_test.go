@@ -0,0 +1,174 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_makeMessageTable(t *testing.T) {
+	table := map[uint32]string{
+		1:          "One",
+		2:          "Two",
+		4:          "Four",
+		0xC0020001: "Erreur",
+	}
+
+	data := makeMessageTable(table)
+	want := []byte{
+		3, 0, 0, 0,
+		1, 0, 0, 0, 2, 0, 0, 0, 0x28, 0, 0, 0,
+		4, 0, 0, 0, 4, 0, 0, 0, 0x40, 0, 0, 0,
+		1, 0, 2, 0xC0, 1, 0, 2, 0xC0, 0x50, 0, 0, 0,
+		12, 0, 1, 0, 'O', 0, 'n', 0, 'e', 0, 0, 0,
+		12, 0, 1, 0, 'T', 0, 'w', 0, 'o', 0, 0, 0,
+		16, 0, 1, 0, 'F', 0, 'o', 0, 'u', 0, 'r', 0, 0, 0, 0, 0,
+		20, 0, 1, 0, 'E', 0, 'r', 0, 'r', 0, 'e', 0, 'u', 0, 'r', 0, 0, 0, 0, 0,
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("makeMessageTable() = %v, want %v", data, want)
+	}
+
+	messages, err := readMessageTable(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMessages := []message{
+		{ID: 1, Text: "One"},
+		{ID: 2, Text: "Two"},
+		{ID: 4, Text: "Four"},
+		{ID: 1, Severity: "error", Facility: 2, Text: "Erreur"},
+	}
+	if !reflect.DeepEqual(messages, wantMessages) {
+		t.Errorf("readMessageTable() = %v, want %v", messages, wantMessages)
+	}
+}
+
+func Test_readMessageTable(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []message
+		err  bool
+	}{
+		{name: "empty", data: []byte{}, err: true},
+		{name: "no blocks", data: []byte{0, 0, 0, 0}},
+		{name: "truncated blocks", data: []byte{1, 0, 0, 0, 1, 0, 0, 0}, err: true},
+		{name: "bad range", data: []byte{1, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 16, 0, 0, 0}, err: true},
+		{name: "bad offset", data: []byte{1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 17, 0, 0, 0}, err: true},
+		{name: "bad length", data: []byte{1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 16, 0, 0, 0, 2, 0, 0, 0}, err: true},
+		{
+			name: "ansi",
+			data: []byte{1, 0, 0, 0, 1, 0, 0, 0x20, 1, 0, 0, 0x20, 16, 0, 0, 0, 8, 0, 0, 0, 'H', 0xE9, 0, 0},
+			want: []message{{ID: 1, Customer: true, Text: "Hé"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readMessageTable(tt.data)
+			if (err != nil) != tt.err {
+				t.Fatalf("readMessageTable() error = %v, want error %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readMessageTable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseMC(t *testing.T) {
+	rs := &winres.ResourceSet{}
+	err := importMessageTable(rs, "_testdata", winres.ID(1), 0, "messages.mc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := readMessageTable(rs.Get(winres.RT_MESSAGETABLE, winres.ID(1), 0x40C))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []message{
+		{ID: 2, Severity: "informational", Facility: 2, Text: "Service %1 démarré."},
+		{ID: 0x10, Severity: "warning", Text: "Première ligne\r\nDeuxième ligne\r\n"},
+		{ID: 1, Severity: "error", Facility: 2, Text: "Vous avez choisi une commande incorrecte.\r\n"},
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("parseMC() = %v, want %v", messages, want)
+	}
+
+	rs = &winres.ResourceSet{}
+	err = importMessageTable(rs, "_testdata", winres.ID(1), 0x409, "messages.mc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Count() != 1 {
+		t.Errorf("expected only one language, got %d resources", rs.Count())
+	}
+	if importMessageTable(rs, "_testdata", winres.ID(1), 0x407, "messages.mc") == nil {
+		t.Error("expected an error for a missing language")
+	}
+}
+
+func Test_parseMC_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		mc   string
+	}{
+		{"empty", ""},
+		{"syntax", "MessageId 1"},
+		{"keyword", "Hello=1"},
+		{"severity", "MessageId=1\nSeverity=Fatal"},
+		{"facility", "MessageId=1\nFacility=Unknown"},
+		{"language", "MessageId=1\nLanguage=Klingon\nText\n."},
+		{"language first", "Language=English\nText\n."},
+		{"unterminated text", "MessageId=1\nLanguage=English\nText"},
+		{"unterminated list", "LanguageNames=(English=0x409:MSG00409"},
+		{"id", "MessageId=0x10000\nLanguage=English\nText\n."},
+		{"names", "SeverityNames=(Fatal)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseMC([]byte(tt.mc)); err == nil {
+				t.Error("parseMC() should fail")
+			}
+		})
+	}
+}
+
+func Test_importMessageTable(t *testing.T) {
+	rs := &winres.ResourceSet{}
+	err := importMessageTable(rs, "", winres.ID(1), 0x409, []interface{}{
+		map[string]interface{}{"id": 1.0, "text": "Hello"},
+		map[string]interface{}{"id": 2.0, "severity": "Error", "facility": 5.0, "customer": true, "text": "Bye"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, _ := readMessageTable(rs.Get(winres.RT_MESSAGETABLE, winres.ID(1), 0x409))
+	want := []message{
+		{ID: 1, Text: "Hello"},
+		{ID: 2, Severity: "error", Facility: 5, Customer: true, Text: "Bye"},
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("importMessageTable() = %v, want %v", messages, want)
+	}
+
+	tests := []struct {
+		name string
+		x    interface{}
+	}{
+		{"severity", []interface{}{map[string]interface{}{"id": 1.0, "severity": "fatal"}}},
+		{"facility", []interface{}{map[string]interface{}{"id": 1.0, "facility": 4096.0}}},
+		{"duplicate", []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 1.0}}},
+		{"type", []interface{}{"hello"}},
+		{"object", map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if importMessageTable(rs, "", winres.ID(1), 0x409, tt.x) == nil {
+				t.Error("importMessageTable() should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,584 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tc-hib/winres"
+	"github.com/tc-hib/winres/version"
+)
+
+const (
+	errRCFileExpected   = "a file name is expected"
+	errRCStringExpected = "a string is expected"
+	errRCMissingEnd     = "missing END"
+	errRCUnsupported    = "resources are not supported in resource scripts"
+)
+
+// Resource types that have a keyword in resource scripts
+var rcResourceTypes = map[string]string{
+	"ICON":         "RT_GROUP_ICON",
+	"CURSOR":       "RT_GROUP_CURSOR",
+	"BITMAP":       "RT_BITMAP",
+	"FONT":         "RT_FONT",
+	"MESSAGETABLE": "RT_MESSAGETABLE",
+	"ANICURSOR":    "RT_ANICURSOR",
+	"ANIICON":      "RT_ANIICON",
+	"HTML":         "RT_HTML",
+	"RCDATA":       "RT_RCDATA",
+	"PLUGPLAY":     "RT_PLUGPLAY",
+	"VXD":          "RT_VXD",
+}
+
+// Predefined resource types whose raw data is imported as is.
+// Other predefined types can only be loaded from a file.
+var rcRawTypes = map[winres.ID]bool{
+	winres.RT_FONTDIR:  true,
+	winres.RT_RCDATA:   true,
+	winres.RT_PLUGPLAY: true,
+	winres.RT_VXD:      true,
+	winres.RT_HTML:     true,
+}
+
+// Statements that are recognized but not supported, they are reported as errors
+// so that a resource is never dropped silently.
+// The error tells how to define them in a json file instead.
+var rcUnsupportedTypes = map[string]string{
+	"TOOLBAR": `add a compiled toolbar to a json file, as type "#241"`,
+}
+
+// Memory flags are obsolete and ignored
+var rcMemoryFlags = map[string]bool{
+	"PRELOAD":     true,
+	"LOADONCALL":  true,
+	"FIXED":       true,
+	"MOVEABLE":    true,
+	"DISCARDABLE": true,
+	"PURE":        true,
+	"IMPURE":      true,
+	"SHARED":      true,
+	"NONSHARED":   true,
+}
+
+// Version flags, as defined in VS_FIXEDFILEINFO
+const (
+	vsFFDebug        = 0x01
+	vsFFPrerelease   = 0x02
+	vsFFPatched      = 0x04
+	vsFFPrivateBuild = 0x08
+	vsFFSpecialBuild = 0x20
+	vftApp           = 1
+	vftDLL           = 2
+)
+
+// rcParser converts the statements of a resource script to a resource set definition,
+// so they can be imported like a json file.
+type rcParser struct {
+	rcStream
+	dir  string
+	lang uint16
+	res  jsonDef
+}
+
+// loadRC reads a resource script (.rc).
+func loadRC(name string) (jsonDef, error) {
+	pp := newRCPreprocessor()
+	err := pp.processFile(name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &rcParser{
+		rcStream: rcStream{toks: pp.toks},
+		dir:      dir,
+		lang:     0x0409,
+		res:      jsonDef{},
+	}
+	return p.res, p.parse()
+}
+
+func (p *rcParser) parse() error {
+	for p.peek() != nil {
+		start := p.pos
+		err := p.statement()
+		if err != nil {
+			// Report the position of the last token read
+			t := &p.toks[start]
+			if p.pos > start {
+				t = &p.toks[p.pos-1]
+			}
+			return fmt.Errorf("%s:%d: %v", t.file, t.line, err)
+		}
+	}
+	return nil
+}
+
+func (p *rcParser) statement() error {
+	t := p.next()
+	switch {
+	case t.is("LANGUAGE"):
+		lang, err := p.language()
+		p.lang = lang
+		return err
+	case t.is("STRINGTABLE"):
+		return p.stringTable()
+	case t.is("VERSION"), t.is("CHARACTERISTICS"):
+		_, err := p.expr()
+		return err
+	}
+
+	name, err := p.identifier(t)
+	if err != nil {
+		return err
+	}
+
+	t = p.next()
+	if t == nil {
+		return errors.New(errRCUnexpectedEnd)
+	}
+	keyword := ""
+	if t.kind == rcWord {
+		keyword = strings.ToUpper(t.text)
+	}
+	switch {
+	case rcUnsupportedTypes[keyword] != "":
+		return fmt.Errorf("%s: %s %s, %s", name, keyword, errRCUnsupported, rcUnsupportedTypes[keyword])
+	case keyword == "DESIGNINFO":
+		// Only used by the resource editor of Visual Studio
+		log.Printf("%s:%d: DESIGNINFO is skipped", t.file, t.line)
+		return p.skipBlock()
+	case keyword == "VERSIONINFO":
+		return p.versionInfo(name)
+	case keyword == "ACCELERATORS":
+		return p.accelerators(name)
+	case keyword == "MENU", keyword == "MENUEX":
+		return p.menu(name, keyword == "MENUEX")
+	case keyword == "DIALOG", keyword == "DIALOGEX":
+		return p.dialog(name, keyword == "DIALOGEX")
+	}
+
+	typ := rcResourceTypes[keyword]
+	if typ == "" {
+		typ, err = p.identifier(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	lang, err := p.options()
+	if err != nil {
+		return err
+	}
+
+	t = p.next()
+	switch {
+	case t == nil:
+		return errors.New(errRCUnexpectedEnd)
+	case t.isBegin():
+		if !rcRawData(typ) {
+			return fmt.Errorf("%s: %s", typ, errRCFileExpected)
+		}
+		data, err := p.rawData()
+		if err != nil {
+			return err
+		}
+		p.add(typ, name, langString(lang), data)
+	case t.kind == rcString, t.kind == rcWord:
+		p.add(typ, name, langString(lang), p.fileName(t))
+	default:
+		return errors.New(errRCFileExpected)
+	}
+
+	return nil
+}
+
+func (p *rcParser) add(t, r, l string, v interface{}) {
+	if p.res[t] == nil {
+		p.res[t] = make(map[string]map[string]interface{})
+	}
+	if p.res[t][r] == nil {
+		p.res[t][r] = make(map[string]interface{})
+	}
+	p.res[t][r][l] = v
+}
+
+// rcRawData tells if a BEGIN ... END block of raw data can define a resource of the given type,
+// whether the type is a keyword or a number.
+func rcRawData(typ string) bool {
+	id, ok := typeIDFromString[typ]
+	if !ok {
+		id, ok = stringToIdentifier(typ).(winres.ID)
+	}
+	if _, predefined := typeIDToString[id]; !ok || !predefined {
+		return true
+	}
+	return rcRawTypes[id]
+}
+
+func langString(langID uint16) string {
+	return fmt.Sprintf("%04X", langID)
+}
+
+// identifier reads the name of a resource, or of a type.
+// Like rc.exe, names are converted to upper case.
+func (p *rcParser) identifier(t *rcToken) (string, error) {
+	switch t.kind {
+	case rcWord:
+		return strings.ToUpper(t.text), nil
+	case rcString:
+		return strings.ToUpper(unescapeRC(t.text, t.wide)), nil
+	}
+	p.pos--
+	v, err := p.expr()
+	if err != nil {
+		return "", err
+	}
+	return "#" + strconv.Itoa(int(uint16(v.n))), nil
+}
+
+func (p *rcParser) language() (uint16, error) {
+	lang, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	err = p.expect(",")
+	if err != nil {
+		return 0, err
+	}
+	sub, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	return uint16(sub.n<<10 | lang.n&0x3FF), nil
+}
+
+// options skips memory flags and reads optional statements.
+// It returns the language of the resource.
+func (p *rcParser) options() (uint16, error) {
+	lang := p.lang
+	for {
+		t := p.peek()
+		if t == nil || t.kind != rcWord {
+			return lang, nil
+		}
+		keyword := strings.ToUpper(t.text)
+		switch {
+		case rcMemoryFlags[keyword]:
+			p.pos++
+		case keyword == "LANGUAGE":
+			p.pos++
+			l, err := p.language()
+			if err != nil {
+				return 0, err
+			}
+			lang = l
+		case keyword == "VERSION", keyword == "CHARACTERISTICS":
+			p.pos++
+			if _, err := p.expr(); err != nil {
+				return 0, err
+			}
+		default:
+			return lang, nil
+		}
+	}
+}
+
+// fileName returns the path of a file relative to the main script.
+//
+// Backslashes are path separators, they may be escaped in a string.
+func (p *rcParser) fileName(t *rcToken) string {
+	name := t.text
+	if t.kind == rcString {
+		name = strings.ReplaceAll(name, `\\`, `\`)
+	}
+	name = strings.ReplaceAll(name, `\`, "/")
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(t.file), name)
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return name
+	}
+	rel, err := filepath.Rel(p.dir, abs)
+	if err != nil {
+		return name
+	}
+	return rel
+}
+
+func (p *rcParser) skipBlock() error {
+	depth := 0
+	for {
+		t := p.next()
+		switch {
+		case t == nil:
+			return errors.New(errRCMissingEnd)
+		case t.isBegin():
+			depth++
+		case t.isEnd():
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+func (p *rcParser) begin() error {
+	t := p.next()
+	if t == nil {
+		return errors.New(errRCUnexpectedEnd)
+	}
+	if !t.isBegin() {
+		return fmt.Errorf("expected BEGIN, found %q", t.text)
+	}
+	return nil
+}
+
+// rawData reads the content of a user defined resource, up to END.
+//
+// Numbers are 16-bit words unless they have an "L" suffix.
+// Strings are not NUL terminated.
+func (p *rcParser) rawData() ([]byte, error) {
+	data := []byte{}
+	for {
+		t := p.peek()
+		switch {
+		case t == nil:
+			return nil, errors.New(errRCMissingEnd)
+		case t.isEnd():
+			p.pos++
+			return data, nil
+		case t.kind == rcString:
+			p.pos++
+			s := unescapeRC(t.text, t.wide)
+			if t.wide {
+				for _, u := range utf16.Encode([]rune(s)) {
+					data = appendUint16(data, u)
+				}
+			} else {
+				data = append(data, s...)
+			}
+		default:
+			v, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			data = appendUint16(data, uint16(v.n))
+			if v.long {
+				data = appendUint16(data, uint16(v.n>>16))
+			}
+		}
+		p.accept(",")
+	}
+}
+
+func (p *rcParser) stringTable() error {
+	lang, err := p.options()
+	if err != nil {
+		return err
+	}
+	err = p.begin()
+	if err != nil {
+		return err
+	}
+
+	for {
+		t := p.peek()
+		if t == nil {
+			return errors.New(errRCMissingEnd)
+		}
+		if t.isEnd() {
+			p.pos++
+			return nil
+		}
+
+		id, err := p.expr()
+		if err != nil {
+			return err
+		}
+		p.accept(",")
+
+		t = p.next()
+		if t == nil || t.kind != rcString {
+			return errors.New(errRCStringExpected)
+		}
+		s := unescapeRC(t.text, true)
+		for p.peek() != nil && p.peek().kind == rcString {
+			t = p.next()
+			s += unescapeRC(t.text, true)
+		}
+
+		p.add("RT_STRING", langString(lang), strconv.Itoa(int(uint16(id.n))), s)
+	}
+}
+
+// versionInfo reads a VERSIONINFO statement.
+// Translations are deduced from the string tables, the VarFileInfo block is ignored.
+func (p *rcParser) versionInfo(name string) error {
+	lang, err := p.options()
+	if err != nil {
+		return err
+	}
+
+	vi := &version.Info{}
+	var flags, mask int64 = 0, -1
+	for {
+		t := p.next()
+		if t == nil {
+			return errors.New(errRCUnexpectedEnd)
+		}
+		if t.isBegin() {
+			break
+		}
+
+		var v rcValue
+		switch {
+		case t.is("FILEVERSION"):
+			vi.FileVersion, err = p.version()
+		case t.is("PRODUCTVERSION"):
+			vi.ProductVersion, err = p.version()
+		case t.is("FILEFLAGSMASK"):
+			v, err = p.expr()
+			mask = v.n
+		case t.is("FILEFLAGS"):
+			v, err = p.expr()
+			flags = v.n
+		case t.is("FILETYPE"):
+			v, err = p.expr()
+			switch v.n {
+			case vftApp:
+				vi.Type = version.App
+			case vftDLL:
+				vi.Type = version.DLL
+			default:
+				vi.Type = version.Unknown
+			}
+		case t.is("FILEOS"), t.is("FILESUBTYPE"):
+			_, err = p.expr()
+		default:
+			err = fmt.Errorf("unexpected %q", t.text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	flags &= mask
+	vi.Flags.Debug = flags&vsFFDebug != 0
+	vi.Flags.Prerelease = flags&vsFFPrerelease != 0
+	vi.Flags.Patched = flags&vsFFPatched != 0
+	vi.Flags.PrivateBuild = flags&vsFFPrivateBuild != 0
+	vi.Flags.SpecialBuild = flags&vsFFSpecialBuild != 0
+
+	err = p.versionBlock(vi, "", 0)
+	if err != nil {
+		return err
+	}
+
+	p.add("RT_VERSION", name, langString(lang), vi)
+	return nil
+}
+
+// version reads up to 4 comma separated numbers.
+func (p *rcParser) version() ([4]uint16, error) {
+	var v [4]uint16
+	for i := range v {
+		n, err := p.expr()
+		if err != nil {
+			return v, err
+		}
+		v[i] = uint16(n.n)
+		if !p.accept(",") {
+			break
+		}
+	}
+	return v, nil
+}
+
+// versionBlock reads the content of a BLOCK, up to END.
+// Values are only kept in string tables, which are blocks of "StringFileInfo".
+func (p *rcParser) versionBlock(vi *version.Info, parent string, langID uint16) error {
+	for {
+		t := p.next()
+		switch {
+		case t == nil:
+			return errors.New(errRCMissingEnd)
+		case t.isEnd():
+			return nil
+		case t.is("BLOCK"):
+			t = p.next()
+			if t == nil || t.kind != rcString {
+				return errors.New(errRCStringExpected)
+			}
+			name := unescapeRC(t.text, t.wide)
+			var l uint64
+			if strings.EqualFold(parent, "StringFileInfo") {
+				var err error
+				if len(name) >= 4 {
+					l, err = strconv.ParseUint(name[:4], 16, 16)
+				}
+				if len(name) < 4 || err != nil {
+					return fmt.Errorf("invalid string table name %q", name)
+				}
+			}
+			err := p.begin()
+			if err != nil {
+				return err
+			}
+			err = p.versionBlock(vi, name, uint16(l))
+			if err != nil {
+				return err
+			}
+		case t.is("VALUE"):
+			t = p.next()
+			if t == nil || t.kind != rcString {
+				return errors.New(errRCStringExpected)
+			}
+			key := unescapeRC(t.text, t.wide)
+			value, err := p.versionValue()
+			if err != nil {
+				return err
+			}
+			if parent != "" && !strings.EqualFold(parent, "StringFileInfo") && !strings.EqualFold(parent, "VarFileInfo") {
+				err = vi.Set(langID, key, strings.TrimRight(value, "\x00"))
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("unexpected %q", t.text)
+		}
+	}
+}
+
+// versionValue reads the comma separated values that follow a key.
+// Strings are concatenated, and numbers are ignored.
+func (p *rcParser) versionValue() (string, error) {
+	var value string
+	for {
+		p.accept(",")
+		t := p.peek()
+		switch {
+		case t == nil, t.isEnd(), t.is("BLOCK"), t.is("VALUE"):
+			return value, nil
+		case t.kind == rcString:
+			p.pos++
+			value += unescapeRC(t.text, true)
+		default:
+			if _, err := p.expr(); err != nil {
+				return "", err
+			}
+		}
+	}
+}
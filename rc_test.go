@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres"
+	"github.com/tc-hib/winres/version"
+)
+
+func Test_loadRC(t *testing.T) {
+	res, err := loadRC(filepath.Join("_testdata", "app.rc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []struct {
+		t, r, l string
+		file    string
+	}{
+		{"RT_GROUP_ICON", "#1", "0409", "en.ico"},
+		{"RT_GROUP_CURSOR", "#2", "0409", "cursor.cur"},
+		{"RT_BITMAP", "LOGO", "0409", "image.bmp"},
+		{"RT_MESSAGETABLE", "#1", "0409", "messages.mc"},
+		{"#24", "#1", "0409", "app.manifest"},
+		{"MYTYPE", "CONFIG", "0409", "resource.h"},
+	}
+	for _, f := range files {
+		if res[f.t][f.r][f.l] != f.file {
+			t.Errorf("[%s][%s][%s] = %v, want %q", f.t, f.r, f.l, res[f.t][f.r][f.l], f.file)
+		}
+	}
+
+	strings := map[string]map[string]interface{}{
+		"0409": {"1001": "Hello\tworld", "1002": `Say "bye"`},
+		"040C": {"1001": "Bonjour"},
+	}
+	if !reflect.DeepEqual(res["RT_STRING"], strings) {
+		t.Errorf("RT_STRING = %v, want %v", res["RT_STRING"], strings)
+	}
+
+	data := []byte{'A', 'B', 0, 0x43, 0x44, 0x44, 0x45, 0x46, 0x47, 0xE9, 0}
+	if !bytes.Equal(res["RT_RCDATA"]["DATA"]["0409"].([]byte), data) {
+		t.Errorf("RT_RCDATA = %v, want %v", res["RT_RCDATA"]["DATA"]["0409"], data)
+	}
+	if _, ok := res["RT_RCDATA"]["NOPE"]; ok {
+		t.Error("conditional block should be skipped")
+	}
+
+	accels := []accelerator{
+		{Key: "^C", Type: keyTypeASCII, ID: 200},
+		{Key: "a", Type: keyTypeASCII, Modifiers: "Alt", ID: 201},
+		{Key: "VK_F5", ID: 202},
+		{Key: "S", Modifiers: "Ctrl,Shift", NoInvert: true, ID: 203},
+		{Key: "0x2E", ID: 204},
+	}
+	if !reflect.DeepEqual(res["RT_ACCELERATOR"]["#100"]["0409"], accels) {
+		t.Errorf("RT_ACCELERATOR = %+v, want %+v", res["RT_ACCELERATOR"]["#100"]["0409"], accels)
+	}
+
+	menu := []menuItem{
+		{Text: "&File", Items: []menuItem{
+			{Text: "&Copy\tCtrl+C", ID: 200, Flags: "checked,grayed"},
+			{Separator: true},
+			{Text: "E&xit", ID: 205},
+		}},
+		{Text: "&Help", ID: 206, Flags: "rightjustify"},
+	}
+	if !reflect.DeepEqual(res["RT_MENU"]["#100"]["0409"], menu) {
+		t.Errorf("RT_MENU #100 = %+v, want %+v", res["RT_MENU"]["#100"]["0409"], menu)
+	}
+	menuEx := []menuItem{
+		{Text: "&Edit", HelpID: 42, Items: []menuItem{
+			{Text: "&All", ID: 201, Flags: "radiocheck,checked,default"},
+			{Separator: true},
+		}},
+	}
+	if !reflect.DeepEqual(res["RT_MENU"]["CONTEXT"]["0409"], menuEx) {
+		t.Errorf("RT_MENU CONTEXT = %+v, want %+v", res["RT_MENU"]["CONTEXT"]["0409"], menuEx)
+	}
+
+	dlg := &dialog{
+		Width:  200,
+		Height: 80,
+		Title:  "About",
+		Style:  "0x80C80080",
+		Menu:   "#100",
+		Font:   &dialogFont{Name: "MS Shell Dlg", Size: 8, Weight: 400, Charset: 1},
+		Controls: []dialogControl{
+			{Class: "STATIC", Text: "#1", ID: 0xFFFFFFFF, X: 8, Y: 8, Style: "0x50000003"},
+			{Class: "STATIC", Text: "Version 1.2", ID: 0xFFFFFFFF, X: 40, Y: 8, Width: 120, Height: 8, Style: "0x50000000"},
+			{Class: "EDIT", ID: 300, X: 40, Y: 24, Width: 120, Height: 14, Style: "0x50810080", ExStyle: "0x200"},
+			{Class: "SysListView32", ID: 301, X: 8, Y: 40, Width: 100, Height: 30, Style: "0x50800001"},
+			{Class: "BUTTON", Text: "OK", ID: 1, X: 140, Y: 60, Width: 50, Height: 14, Style: "0x50010001"},
+		},
+	}
+	if !reflect.DeepEqual(res["RT_DIALOG"]["#101"]["0409"], dlg) {
+		t.Errorf("RT_DIALOG = %+v, want %+v", res["RT_DIALOG"]["#101"]["0409"], dlg)
+	}
+
+	vi := res["RT_VERSION"]["#1"]["0409"].(*version.Info)
+	if vi.FileVersion != [4]uint16{1, 2, 3, 4} || vi.ProductVersion != [4]uint16{1, 2, 0, 0} {
+		t.Errorf("wrong versions %v %v", vi.FileVersion, vi.ProductVersion)
+	}
+	if !vi.Flags.Debug || !vi.Flags.Prerelease || vi.Flags.Patched || vi.Type != version.DLL {
+		t.Errorf("wrong flags or type %+v %v", vi.Flags, vi.Type)
+	}
+	table := version.LangTable{
+		0x409: {"CompanyName": "Company", "FileDescription": "Test library", "ProductVersion": "1.2"},
+		0x40C: {"FileDescription": "Bibliothèque de test"},
+	}
+	if !reflect.DeepEqual(vi.Table(), table) {
+		t.Errorf("wrong string tables %v", vi.Table())
+	}
+}
+
+func Test_importResources_RC(t *testing.T) {
+	rs := &winres.ResourceSet{}
+	err := importResources(rs, filepath.Join("_testdata", "app.rc"), importOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rs.Get(winres.RT_STRING, winres.ID(63), 0x40C) == nil {
+		t.Error("missing string block")
+	}
+	if rs.Get(winres.RT_MANIFEST, winres.ID(1), 0x409) == nil {
+		t.Error("missing manifest")
+	}
+	if rs.Get(winres.RT_VERSION, winres.ID(1), 0x409) == nil {
+		t.Error("missing version info")
+	}
+	if rs.Get(winres.Name("MYTYPE"), winres.Name("CONFIG"), 0x409) == nil {
+		t.Error("missing user defined resource")
+	}
+	if rs.Get(winres.RT_MESSAGETABLE, winres.ID(1), 0x409) == nil {
+		t.Error("missing message table")
+	}
+	if rs.Get(winres.RT_ACCELERATOR, winres.ID(100), 0x409) == nil {
+		t.Error("missing accelerator table")
+	}
+	if rs.Get(winres.RT_MENU, winres.Name("CONTEXT"), 0x409) == nil {
+		t.Error("missing menu")
+	}
+	if rs.Get(winres.RT_DIALOG, winres.ID(101), 0x409) == nil {
+		t.Error("missing dialog")
+	}
+}
+
+func Test_rcExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		want int64
+		long bool
+	}{
+		{"1", 1, false},
+		{"0x10L", 16, true},
+		{"1 + 2 * 3", 7, false},
+		{"(1 + 2) * 3", 9, false},
+		{"-1 | 0x100", -1, false},
+		{"~0 & 0xFF", 255, false},
+		{"1 << 4 | 2L", 18, true},
+		{"10 / 3 == 3 && !0", 1, false},
+		{"7 % 4 >= 3 || 1 / 1", 1, false},
+		{"010", 8, false},
+		{"08", 8, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			toks, err := tokenizeRC(tt.expr, "", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &rcStream{toks: toks}
+			v, err := s.expr()
+			if err != nil || s.peek() != nil {
+				t.Fatal(err, s.peek())
+			}
+			if v.n != tt.want || v.long != tt.long {
+				t.Errorf("expr() = %v, want %d %v", v, tt.want, tt.long)
+			}
+		})
+	}
+}
+
+func Test_loadRC_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		rc   string
+	}{
+		{"unterminated", `1 RCDATA "abc`},
+		{"if", "#if 1\n1 RCDATA \"a\""},
+		{"endif", "#endif"},
+		{"error", "#error stop"},
+		{"include", `#include "missing.h"`},
+		{"end", "1 RCDATA BEGIN 1, 2"},
+		{"icon block", "1 ICON BEGIN 1 END"},
+		{"manifest block", "1 24 BEGIN \"<assembly/>\" END"},
+		{"message table block", "1 11 BEGIN 1 END"},
+		{"undefined", "STRINGTABLE BEGIN IDS_X \"a\" END"},
+		{"string", "STRINGTABLE BEGIN 1 2 END"},
+		{"language", "LANGUAGE 9"},
+		{"version", "1 VERSIONINFO FILEVERSION 1 BEGIN BLOCK \"StringFileInfo\" BEGIN BLOCK \"x\" BEGIN END END END"},
+		{"division", "1 RCDATA BEGIN 1/0 END"},
+		{"toolbar", "1 TOOLBAR 16, 15 BEGIN BUTTON 100 END"},
+		{"dialog style", "ABOUT DIALOGEX 0, 0, 100, 50 STYLE WS_NOPE BEGIN END"},
+		{"dialog control", "ABOUT DIALOG 0, 0, 100, 50 BEGIN BUTTON \"OK\", 1, 10, 10, 50, 14 END"},
+		{"menu item", "1 MENU BEGIN POPUP \"&File\" BEGIN MENUITEM 100 END END"},
+		{"empty popup", "1 MENU BEGIN POPUP \"&File\" BEGIN END END"},
+		{"menu end", "1 MENUEX BEGIN MENUITEM \"E&xit\", 100"},
+		{"accelerator", "1 ACCELERATORS BEGIN \"^C\" END"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "test.rc")
+			if err := ioutil.WriteFile(name, []byte(tt.rc), 0666); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadRC(name); err == nil {
+				t.Error("loadRC() should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Styles that rc.exe adds to dialogs and controls
+const (
+	wsPopup   = 0x80000000
+	wsChild   = 0x40000000
+	wsVisible = 0x10000000
+	wsCaption = 0x00C00000
+	wsBorder  = 0x00800000
+	wsSysMenu = 0x00080000
+)
+
+// rcControl describes a control statement of a dialog, such as LTEXT or PUSHBUTTON.
+type rcControl struct {
+	class string
+	// style is the default style, WS_CHILD and WS_VISIBLE are always added
+	style string
+	// noText is set for controls that have no text, such as EDITTEXT
+	noText bool
+}
+
+// https://docs.microsoft.com/en-us/windows/win32/menurc/control-statements
+var rcControls = map[string]rcControl{
+	"LTEXT":           {"STATIC", "SS_LEFT,WS_GROUP", false},
+	"RTEXT":           {"STATIC", "SS_RIGHT,WS_GROUP", false},
+	"CTEXT":           {"STATIC", "SS_CENTER,WS_GROUP", false},
+	"ICON":            {"STATIC", "SS_ICON", false},
+	"PUSHBUTTON":      {"BUTTON", "BS_PUSHBUTTON,WS_TABSTOP", false},
+	"DEFPUSHBUTTON":   {"BUTTON", "BS_DEFPUSHBUTTON,WS_TABSTOP", false},
+	"PUSHBOX":         {"BUTTON", "BS_PUSHBOX,WS_TABSTOP", false},
+	"CHECKBOX":        {"BUTTON", "BS_CHECKBOX,WS_TABSTOP", false},
+	"AUTOCHECKBOX":    {"BUTTON", "BS_AUTOCHECKBOX,WS_TABSTOP", false},
+	"STATE3":          {"BUTTON", "BS_3STATE,WS_TABSTOP", false},
+	"AUTO3STATE":      {"BUTTON", "BS_AUTO3STATE,WS_TABSTOP", false},
+	"RADIOBUTTON":     {"BUTTON", "BS_RADIOBUTTON,WS_TABSTOP", false},
+	"AUTORADIOBUTTON": {"BUTTON", "BS_AUTORADIOBUTTON,WS_TABSTOP", false},
+	"GROUPBOX":        {"BUTTON", "BS_GROUPBOX", false},
+	"EDITTEXT":        {"EDIT", "ES_LEFT,WS_BORDER,WS_TABSTOP", true},
+	"LISTBOX":         {"LISTBOX", "LBS_NOTIFY,WS_BORDER", true},
+	"COMBOBOX":        {"COMBOBOX", "CBS_SIMPLE,WS_TABSTOP", true},
+	"SCROLLBAR":       {"SCROLLBAR", "SBS_HORZ", true},
+}
+
+// dialog reads a DIALOG or a DIALOGEX statement.
+//
+// Both are compiled to DIALOGEX templates, with the default styles of rc.exe.
+func (p *rcParser) dialog(name string, ex bool) error {
+	lang, err := p.options()
+	if err != nil {
+		return err
+	}
+
+	dlg := &dialog{}
+	err = p.int16s(&dlg.X, &dlg.Y, &dlg.Width, &dlg.Height)
+	if err != nil {
+		return err
+	}
+	if ex && p.accept(",") {
+		v, err := p.expr()
+		if err != nil {
+			return err
+		}
+		dlg.HelpID = uint32(v.n)
+	}
+
+	var style uint32 = wsPopup | wsBorder | wsSysMenu
+	for {
+		t := p.next()
+		if t == nil {
+			return errors.New(errRCUnexpectedEnd)
+		}
+		if t.isBegin() {
+			break
+		}
+
+		switch {
+		case t.is("STYLE"):
+			style, err = p.style(0, windowStyles, dialogStyles)
+		case t.is("EXSTYLE"):
+			var exStyle uint32
+			exStyle, err = p.style(0, exStyles)
+			dlg.ExStyle = fmt.Sprintf("0x%X", exStyle)
+		case t.is("CAPTION"):
+			dlg.Title, err = p.text()
+		case t.is("CLASS"):
+			dlg.Class, err = p.controlText()
+		case t.is("MENU"):
+			dlg.Menu, err = p.menuName()
+		case t.is("FONT"):
+			dlg.Font, err = p.font(ex)
+		case t.is("LANGUAGE"):
+			lang, err = p.language()
+		case t.is("VERSION"), t.is("CHARACTERISTICS"):
+			_, err = p.expr()
+		default:
+			err = fmt.Errorf("unexpected %q", t.text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if dlg.Title != "" {
+		style |= wsCaption
+	}
+	dlg.Style = fmt.Sprintf("0x%X", style)
+
+	dlg.Controls, err = p.controls(ex)
+	if err != nil {
+		return err
+	}
+
+	p.add("RT_DIALOG", name, langString(lang), dlg)
+	return nil
+}
+
+// controls reads the controls of a dialog, up to END.
+func (p *rcParser) controls(ex bool) ([]dialogControl, error) {
+	var controls []dialogControl
+	for {
+		t := p.next()
+		if t == nil {
+			return nil, errors.New(errRCMissingEnd)
+		}
+
+		var (
+			c   dialogControl
+			err error
+		)
+		def, ok := rcControls[strings.ToUpper(t.text)]
+		switch {
+		case t.isEnd():
+			return controls, nil
+		case t.is("CONTROL"):
+			c, err = p.genericControl()
+		case ok && t.kind == rcWord:
+			c, err = p.control(def, t.is("ICON"))
+		default:
+			return nil, fmt.Errorf("unexpected %q", t.text)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Optional extended style and help ID
+		if p.accept(",") {
+			var exStyle uint32
+			exStyle, err = p.style(0, exStyles)
+			c.ExStyle = fmt.Sprintf("0x%X", exStyle)
+		}
+		if err == nil && ex && p.accept(",") {
+			var v rcValue
+			v, err = p.expr()
+			c.HelpID = uint32(v.n)
+		}
+		// Creation data
+		if err == nil && ex && p.peek().isBegin() {
+			p.pos++
+			c.Data, err = p.rawData()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		controls = append(controls, c)
+	}
+}
+
+// control reads a control statement such as LTEXT, up to its optional style:
+//
+//	LTEXT text, id, x, y, width, height [, style]
+//	EDITTEXT id, x, y, width, height [, style]
+//	ICON text, id, x, y [, width, height [, style]]
+func (p *rcParser) control(def rcControl, icon bool) (dialogControl, error) {
+	c := dialogControl{Class: def.class}
+
+	var err error
+	if !def.noText {
+		c.Text, err = p.controlText()
+		if err != nil {
+			return c, err
+		}
+		p.accept(",")
+	}
+	id, err := p.expr()
+	if err != nil {
+		return c, err
+	}
+	c.ID = uint32(id.n)
+	p.accept(",")
+
+	err = p.int16s(&c.X, &c.Y)
+	if err != nil {
+		return c, err
+	}
+	// The size of an icon is optional
+	hasSize := p.accept(",") || !icon
+	if hasSize {
+		err = p.int16s(&c.Width, &c.Height)
+		if err != nil {
+			return c, err
+		}
+	}
+
+	style, err := parseStyle(def.style, windowStyles, controlStyles, classStyles)
+	if err != nil {
+		return c, err
+	}
+	style |= wsChild | wsVisible
+	if p.accept(",") {
+		style, err = p.style(style, windowStyles, controlStyles, classStyles)
+	}
+	c.Style = fmt.Sprintf("0x%X", style)
+	return c, err
+}
+
+// genericControl reads a CONTROL statement, up to its optional extended style:
+//
+//	CONTROL text, id, class, style, x, y, width, height
+func (p *rcParser) genericControl() (dialogControl, error) {
+	c := dialogControl{}
+
+	var err error
+	c.Text, err = p.controlText()
+	if err != nil {
+		return c, err
+	}
+	p.accept(",")
+	id, err := p.expr()
+	if err != nil {
+		return c, err
+	}
+	c.ID = uint32(id.n)
+	p.accept(",")
+
+	c.Class, err = p.controlText()
+	if err != nil {
+		return c, err
+	}
+	p.accept(",")
+	style, err := p.style(wsChild|wsVisible, windowStyles, controlStyles, classStyles)
+	if err != nil {
+		return c, err
+	}
+	c.Style = fmt.Sprintf("0x%X", style)
+	p.accept(",")
+
+	err = p.int16s(&c.X, &c.Y, &c.Width, &c.Height)
+	return c, err
+}
+
+// font reads the FONT statement of a dialog: size, name, and weight, italic and charset for DIALOGEX.
+func (p *rcParser) font(ex bool) (*dialogFont, error) {
+	size, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.accept(",")
+	f := &dialogFont{Size: uint16(size.n)}
+	f.Name, err = p.text()
+	if err != nil {
+		return nil, err
+	}
+
+	var values [3]int64
+	for i := 0; ex && i < len(values) && p.accept(","); i++ {
+		v, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v.n
+	}
+	f.Weight = uint16(values[0])
+	f.Italic = values[1] != 0
+	f.Charset = uint8(values[2])
+	return f, nil
+}
+
+// style reads a style expression such as "WS_CHILD | WS_VISIBLE | NOT WS_TABSTOP".
+//
+// Names are looked up in the given tables, because system headers are not read.
+// NOT removes bits from the default value.
+func (p *rcParser) style(value uint32, tables ...[]style) (uint32, error) {
+	for {
+		not := p.accept("NOT")
+
+		var bits uint32
+		t := p.peek()
+		if t != nil && t.kind == rcWord {
+			p.pos++
+			n, err := parseStyle(t.text, tables...)
+			if err != nil {
+				return 0, err
+			}
+			bits = n
+		} else {
+			v, err := p.unary()
+			if err != nil {
+				return 0, err
+			}
+			bits = uint32(v.n)
+		}
+
+		if not {
+			value &^= bits
+		} else {
+			value |= bits
+		}
+		if !p.accept("|") {
+			return value, nil
+		}
+	}
+}
+
+// text reads a string.
+func (p *rcParser) text() (string, error) {
+	t := p.next()
+	if t == nil || t.kind != rcString {
+		return "", errors.New(errRCStringExpected)
+	}
+	return unescapeRC(t.text, true), nil
+}
+
+// controlText reads the text or the class of a control, which is either a string, a name, or an ordinal.
+func (p *rcParser) controlText() (string, error) {
+	t := p.next()
+	if t == nil {
+		return "", errors.New(errRCUnexpectedEnd)
+	}
+	if t.kind == rcString {
+		return unescapeRC(t.text, true), nil
+	}
+	return p.identifier(t)
+}
+
+// menuName reads the MENU statement of a dialog, a name or an ordinal.
+func (p *rcParser) menuName() (string, error) {
+	t := p.next()
+	if t == nil {
+		return "", errors.New(errRCUnexpectedEnd)
+	}
+	return p.identifier(t)
+}
+
+// int16s reads comma separated numbers.
+func (p *rcParser) int16s(values ...*int16) error {
+	for i, v := range values {
+		if i > 0 {
+			p.accept(",")
+		}
+		n, err := p.expr()
+		if err != nil {
+			return err
+		}
+		*v = int16(n.n)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Options of MENUITEM and POPUP statements in MENU resources, as JSON flags
+var rcMenuOptions = map[string]string{
+	"CHECKED":      "checked",
+	"GRAYED":       "grayed",
+	"INACTIVE":     "grayed",
+	"HELP":         "rightjustify",
+	"MENUBARBREAK": "menubarbreak",
+	"MENUBREAK":    "menubreak",
+}
+
+// Options of accelerators, as JSON modifiers
+var rcAccelModifiers = map[string]string{
+	"CONTROL": "Ctrl",
+	"ALT":     "Alt",
+	"SHIFT":   "Shift",
+}
+
+// menu reads a MENU or a MENUEX statement.
+func (p *rcParser) menu(name string, ex bool) error {
+	lang, err := p.options()
+	if err != nil {
+		return err
+	}
+	err = p.begin()
+	if err != nil {
+		return err
+	}
+
+	items, err := p.menuItems(ex)
+	if err != nil {
+		return err
+	}
+
+	p.add("RT_MENU", name, langString(lang), items)
+	return nil
+}
+
+// menuItems reads the items of a menu or of a popup, up to END.
+func (p *rcParser) menuItems(ex bool) ([]menuItem, error) {
+	var items []menuItem
+	for {
+		t := p.next()
+		switch {
+		case t == nil:
+			return nil, errors.New(errRCMissingEnd)
+		case t.isEnd():
+			return items, nil
+		case !t.is("MENUITEM") && !t.is("POPUP"):
+			return nil, fmt.Errorf("unexpected %q", t.text)
+		}
+
+		if t.is("MENUITEM") && p.accept("SEPARATOR") {
+			items = append(items, menuItem{Separator: true})
+			continue
+		}
+
+		popup := t.is("POPUP")
+		t = p.next()
+		if t == nil || t.kind != rcString {
+			return nil, errors.New(errRCStringExpected)
+		}
+		item := menuItem{Text: unescapeRC(t.text, true)}
+
+		var err error
+		switch {
+		case ex:
+			err = p.menuExOptions(&item, popup)
+		case popup:
+			item.Flags = p.menuOptions()
+		default:
+			p.accept(",")
+			var id rcValue
+			id, err = p.expr()
+			item.ID = uint32(uint16(id.n))
+			item.Flags = p.menuOptions()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if popup {
+			err = p.begin()
+			if err != nil {
+				return nil, err
+			}
+			item.Items, err = p.menuItems(ex)
+			if err != nil {
+				return nil, err
+			}
+			if len(item.Items) == 0 {
+				return nil, errors.New(errEmptyPopup)
+			}
+		}
+		items = append(items, item)
+	}
+}
+
+// menuOptions reads the options of an item of a MENU, separated by commas or spaces.
+func (p *rcParser) menuOptions() string {
+	var flags []string
+	for {
+		p.accept(",")
+		t := p.peek()
+		if t == nil || t.kind != rcWord {
+			break
+		}
+		f, ok := rcMenuOptions[strings.ToUpper(t.text)]
+		if !ok {
+			break
+		}
+		p.pos++
+		flags = append(flags, f)
+	}
+	return strings.Join(flags, ",")
+}
+
+// menuExOptions reads the optional id, type, state, and help ID of a popup, of an item of a MENUEX.
+func (p *rcParser) menuExOptions(item *menuItem, popup bool) error {
+	n := 3
+	if popup {
+		n = 4
+	}
+	var values [4]uint32
+	for i := 0; i < n && p.accept(","); i++ {
+		if p.peek().is(",") {
+			continue
+		}
+		v, err := p.expr()
+		if err != nil {
+			return err
+		}
+		values[i] = uint32(v.n)
+	}
+
+	item.ID = values[0]
+	item.Separator = values[1]&mftSeparator != 0
+	item.Flags = menuFlagsString(values[1], values[2])
+	item.HelpID = values[3]
+	return nil
+}
+
+// accelerators reads an ACCELERATORS statement.
+func (p *rcParser) accelerators(name string) error {
+	lang, err := p.options()
+	if err != nil {
+		return err
+	}
+	err = p.begin()
+	if err != nil {
+		return err
+	}
+
+	var accels []accelerator
+	for {
+		t := p.peek()
+		if t == nil {
+			return errors.New(errRCMissingEnd)
+		}
+		if t.isEnd() {
+			p.pos++
+			break
+		}
+		a, err := p.accelerator()
+		if err != nil {
+			return err
+		}
+		accels = append(accels, a)
+	}
+
+	p.add("RT_ACCELERATOR", name, langString(lang), accels)
+	return nil
+}
+
+// accelerator reads an entry of an accelerator table: event, id, and options.
+//
+// The event is a string, a virtual key name, or a character code.
+func (p *rcParser) accelerator() (accelerator, error) {
+	var (
+		a    accelerator
+		code int64
+	)
+
+	t := p.peek()
+	switch {
+	case t.kind == rcString:
+		p.pos++
+		a.Key = unescapeRC(t.text, true)
+	case t.kind == rcWord && virtKeys[strings.ToUpper(t.text)] != 0:
+		p.pos++
+		a.Key = strings.ToUpper(t.text)
+	default:
+		v, err := p.expr()
+		if err != nil {
+			return a, err
+		}
+		code = v.n
+	}
+
+	p.accept(",")
+	id, err := p.expr()
+	if err != nil {
+		return a, err
+	}
+	a.ID = uint16(id.n)
+
+	virtKey := false
+	var mods []string
+options:
+	for {
+		p.accept(",")
+		t := p.peek()
+		if t == nil || t.kind != rcWord {
+			break
+		}
+		keyword := strings.ToUpper(t.text)
+		switch {
+		case rcAccelModifiers[keyword] != "":
+			mods = append(mods, rcAccelModifiers[keyword])
+		case keyword == "VIRTKEY":
+			virtKey = true
+		case keyword == "NOINVERT":
+			a.NoInvert = true
+		case keyword == "ASCII":
+		default:
+			break options
+		}
+		p.pos++
+	}
+	a.Modifiers = strings.Join(mods, ",")
+
+	if !virtKey {
+		a.Type = keyTypeASCII
+	}
+	if a.Key == "" {
+		switch {
+		case virtKey:
+			a.Key = fmt.Sprintf("0x%02X", code)
+		case code > 0 && code < 0x20:
+			a.Key = "^" + string(rune(code+'@'))
+		default:
+			a.Key = string(rune(code))
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,875 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	errRCUnterminatedString = "unterminated string"
+	errRCUnbalancedIf       = "unbalanced #if"
+	errRCUnexpectedEnd      = "unexpected end of file"
+	errRCDivisionByZero     = "division by zero"
+	errRCIncludeDepth       = "too many nested #include"
+)
+
+type rcTokenKind int
+
+const (
+	rcWord rcTokenKind = iota
+	rcNumber
+	rcString
+	rcPunct
+)
+
+// rcToken is a token of a resource script.
+//
+// The text of a string token is kept as it is written, without its quotes,
+// because file names and texts do not handle escape sequences the same way.
+type rcToken struct {
+	kind rcTokenKind
+	text string
+	wide bool
+	file string
+	line int
+}
+
+func (t *rcToken) is(s string) bool {
+	return t != nil && t.kind != rcString && strings.EqualFold(t.text, s)
+}
+
+func (t *rcToken) isBegin() bool {
+	return t.is("BEGIN") || t.is("{")
+}
+
+func (t *rcToken) isEnd() bool {
+	return t.is("END") || t.is("}")
+}
+
+// Constants that are usually defined in system headers.
+// Those headers are not available, so they are built in.
+var rcBuiltinDefines = map[string]int64{
+	"RC_INVOKED": 1,
+	"_WIN32":     1,
+
+	"RT_CURSOR":       1,
+	"RT_BITMAP":       2,
+	"RT_ICON":         3,
+	"RT_MENU":         4,
+	"RT_DIALOG":       5,
+	"RT_STRING":       6,
+	"RT_FONTDIR":      7,
+	"RT_FONT":         8,
+	"RT_ACCELERATOR":  9,
+	"RT_RCDATA":       10,
+	"RT_MESSAGETABLE": 11,
+	"RT_GROUP_CURSOR": 12,
+	"RT_GROUP_ICON":   14,
+	"RT_VERSION":      16,
+	"RT_DLGINCLUDE":   17,
+	"RT_PLUGPLAY":     19,
+	"RT_VXD":          20,
+	"RT_ANICURSOR":    21,
+	"RT_ANIICON":      22,
+	"RT_HTML":         23,
+	"RT_MANIFEST":     24,
+
+	"CREATEPROCESS_MANIFEST_RESOURCE_ID":                 1,
+	"ISOLATIONAWARE_MANIFEST_RESOURCE_ID":                2,
+	"ISOLATIONAWARE_NOSTATICIMPORT_MANIFEST_RESOURCE_ID": 3,
+
+	"VS_VERSION_INFO":      1,
+	"VS_FF_DEBUG":          0x01,
+	"VS_FF_PRERELEASE":     0x02,
+	"VS_FF_PATCHED":        0x04,
+	"VS_FF_PRIVATEBUILD":   0x08,
+	"VS_FF_INFOINFERRED":   0x10,
+	"VS_FF_SPECIALBUILD":   0x20,
+	"VS_FFI_FILEFLAGSMASK": 0x3F,
+	"VOS_UNKNOWN":          0,
+	"VOS_DOS":              0x00010000,
+	"VOS_NT":               0x00040000,
+	"VOS__WINDOWS16":       0x00000001,
+	"VOS__WINDOWS32":       0x00000004,
+	"VOS_DOS_WINDOWS16":    0x00010001,
+	"VOS_DOS_WINDOWS32":    0x00010004,
+	"VOS_NT_WINDOWS32":     0x00040004,
+	"VFT_UNKNOWN":          0,
+	"VFT_APP":              1,
+	"VFT_DLL":              2,
+	"VFT_DRV":              3,
+	"VFT_FONT":             4,
+	"VFT_VXD":              5,
+	"VFT_STATIC_LIB":       7,
+	"VFT2_UNKNOWN":         0,
+
+	"IDC_STATIC": -1,
+	"IDOK":       1,
+	"IDCANCEL":   2,
+	"IDABORT":    3,
+	"IDRETRY":    4,
+	"IDIGNORE":   5,
+	"IDYES":      6,
+	"IDNO":       7,
+	"IDCLOSE":    8,
+	"IDHELP":     9,
+
+	"MFT_STRING":       0,
+	"MFT_BITMAP":       0x0004,
+	"MFT_MENUBARBREAK": 0x0020,
+	"MFT_MENUBREAK":    0x0040,
+	"MFT_OWNERDRAW":    0x0100,
+	"MFT_RADIOCHECK":   0x0200,
+	"MFT_SEPARATOR":    0x0800,
+	"MFT_RIGHTORDER":   0x2000,
+	"MFT_RIGHTJUSTIFY": 0x4000,
+	"MFS_ENABLED":      0,
+	"MFS_UNCHECKED":    0,
+	"MFS_UNHILITE":     0,
+	"MFS_GRAYED":       0x0003,
+	"MFS_DISABLED":     0x0003,
+	"MFS_CHECKED":      0x0008,
+	"MFS_HILITE":       0x0080,
+	"MFS_DEFAULT":      0x1000,
+
+	"LANG_NEUTRAL":    0x00,
+	"LANG_INVARIANT":  0x7F,
+	"LANG_ARABIC":     0x01,
+	"LANG_BULGARIAN":  0x02,
+	"LANG_CATALAN":    0x03,
+	"LANG_CHINESE":    0x04,
+	"LANG_CZECH":      0x05,
+	"LANG_DANISH":     0x06,
+	"LANG_GERMAN":     0x07,
+	"LANG_GREEK":      0x08,
+	"LANG_ENGLISH":    0x09,
+	"LANG_SPANISH":    0x0A,
+	"LANG_FINNISH":    0x0B,
+	"LANG_FRENCH":     0x0C,
+	"LANG_HEBREW":     0x0D,
+	"LANG_HUNGARIAN":  0x0E,
+	"LANG_ICELANDIC":  0x0F,
+	"LANG_ITALIAN":    0x10,
+	"LANG_JAPANESE":   0x11,
+	"LANG_KOREAN":     0x12,
+	"LANG_DUTCH":      0x13,
+	"LANG_NORWEGIAN":  0x14,
+	"LANG_POLISH":     0x15,
+	"LANG_PORTUGUESE": 0x16,
+	"LANG_ROMANIAN":   0x18,
+	"LANG_RUSSIAN":    0x19,
+	"LANG_CROATIAN":   0x1A,
+	"LANG_SERBIAN":    0x1A,
+	"LANG_SLOVAK":     0x1B,
+	"LANG_SWEDISH":    0x1D,
+	"LANG_THAI":       0x1E,
+	"LANG_TURKISH":    0x1F,
+	"LANG_UKRAINIAN":  0x22,
+	"LANG_VIETNAMESE": 0x2A,
+
+	"SUBLANG_NEUTRAL":              0x00,
+	"SUBLANG_DEFAULT":              0x01,
+	"SUBLANG_SYS_DEFAULT":          0x02,
+	"SUBLANG_CUSTOM_DEFAULT":       0x03,
+	"SUBLANG_ENGLISH_US":           0x01,
+	"SUBLANG_ENGLISH_UK":           0x02,
+	"SUBLANG_ENGLISH_AUS":          0x03,
+	"SUBLANG_ENGLISH_CAN":          0x04,
+	"SUBLANG_FRENCH":               0x01,
+	"SUBLANG_FRENCH_BELGIAN":       0x02,
+	"SUBLANG_FRENCH_CANADIAN":      0x03,
+	"SUBLANG_FRENCH_SWISS":         0x04,
+	"SUBLANG_GERMAN":               0x01,
+	"SUBLANG_GERMAN_SWISS":         0x02,
+	"SUBLANG_GERMAN_AUSTRIAN":      0x03,
+	"SUBLANG_SPANISH":              0x01,
+	"SUBLANG_SPANISH_MEXICAN":      0x02,
+	"SUBLANG_SPANISH_MODERN":       0x03,
+	"SUBLANG_ITALIAN":              0x01,
+	"SUBLANG_PORTUGUESE":           0x02,
+	"SUBLANG_PORTUGUESE_BRAZILIAN": 0x01,
+	"SUBLANG_CHINESE_TRADITIONAL":  0x01,
+	"SUBLANG_CHINESE_SIMPLIFIED":   0x02,
+	"SUBLANG_JAPANESE_JAPAN":       0x01,
+	"SUBLANG_KOREAN":               0x01,
+	"SUBLANG_DUTCH":                0x01,
+	"SUBLANG_RUSSIAN_RUSSIA":       0x01,
+	"SUBLANG_POLISH_POLAND":        0x01,
+	"SUBLANG_SWEDISH":              0x01,
+}
+
+// System headers that are silently ignored when they can't be found,
+// because their usual constants are built in.
+var rcSystemHeaders = map[string]bool{
+	"windows.h":  true,
+	"winres.h":   true,
+	"winresrc.h": true,
+	"afxres.h":   true,
+	"winver.h":   true,
+	"verrsrc.h":  true,
+	"winuser.h":  true,
+	"winnt.h":    true,
+	"commctrl.h": true,
+	"richedit.h": true,
+	"dlgs.h":     true,
+	"ntverp.h":   true,
+	"common.ver": true,
+}
+
+const rcMaxIncludeDepth = 32
+
+// rcPreprocessor handles a subset of the C preprocessor, as rc.exe does:
+// #include, object-like #define, #undef, and conditional blocks.
+type rcPreprocessor struct {
+	defines map[string][]rcToken
+	toks    []rcToken
+	depth   int
+}
+
+func newRCPreprocessor() *rcPreprocessor {
+	pp := &rcPreprocessor{defines: make(map[string][]rcToken)}
+	for name, value := range rcBuiltinDefines {
+		if value < 0 {
+			pp.defines[name] = []rcToken{{kind: rcPunct, text: "-"}, {kind: rcNumber, text: strconv.FormatInt(-value, 10)}}
+		} else {
+			pp.defines[name] = []rcToken{{kind: rcNumber, text: strconv.FormatInt(value, 10)}}
+		}
+	}
+	return pp
+}
+
+type rcCond struct {
+	parent bool
+	active bool
+	done   bool
+}
+
+// processFile preprocesses a file and appends its tokens.
+//
+// Like rc.exe, it only looks for directives in C headers (.h and .c files).
+func (pp *rcPreprocessor) processFile(name string, directivesOnly bool) error {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(stripComments(decodeRC(b)), "\n")
+
+	var conds []rcCond
+	active := true
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+
+		if !strings.HasPrefix(line, "#") {
+			if !active || directivesOnly {
+				continue
+			}
+			toks, err := tokenizeRC(line, name, lineNo)
+			if err != nil {
+				return err
+			}
+			pp.toks = append(pp.toks, pp.expand(toks, nil)...)
+			continue
+		}
+
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + " " + strings.TrimSpace(lines[i])
+		}
+		directive, rest := splitDirective(line[1:])
+
+		switch directive {
+		case "if", "ifdef", "ifndef":
+			c := rcCond{parent: active}
+			if active {
+				c.active, err = pp.condition(directive, rest, name, lineNo)
+				if err != nil {
+					return err
+				}
+				c.done = c.active
+			}
+			conds = append(conds, c)
+		case "elif":
+			if len(conds) == 0 {
+				return fmt.Errorf("%s:%d: %s", name, lineNo, errRCUnbalancedIf)
+			}
+			c := &conds[len(conds)-1]
+			c.active = false
+			if c.parent && !c.done {
+				c.active, err = pp.condition("if", rest, name, lineNo)
+				if err != nil {
+					return err
+				}
+				c.done = c.active
+			}
+		case "else":
+			if len(conds) == 0 {
+				return fmt.Errorf("%s:%d: %s", name, lineNo, errRCUnbalancedIf)
+			}
+			c := &conds[len(conds)-1]
+			c.active = c.parent && !c.done
+			c.done = true
+		case "endif":
+			if len(conds) == 0 {
+				return fmt.Errorf("%s:%d: %s", name, lineNo, errRCUnbalancedIf)
+			}
+			conds = conds[:len(conds)-1]
+		}
+		active = len(conds) == 0 || conds[len(conds)-1].parent && conds[len(conds)-1].active
+		if !active {
+			continue
+		}
+
+		switch directive {
+		case "define":
+			err = pp.define(rest, name, lineNo)
+		case "undef":
+			delete(pp.defines, strings.TrimSpace(rest))
+		case "include":
+			err = pp.include(rest, name, lineNo)
+		case "error":
+			err = fmt.Errorf("%s:%d: #error %s", name, lineNo, rest)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(conds) > 0 {
+		return fmt.Errorf("%s: %s", name, errRCUnbalancedIf)
+	}
+	return nil
+}
+
+func splitDirective(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return !isRCWordRune(r) })
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+func (pp *rcPreprocessor) define(s string, file string, line int) error {
+	name, rest := splitDirective(s)
+	if name == "" {
+		return fmt.Errorf("%s:%d: invalid #define", file, line)
+	}
+	if strings.HasPrefix(s[len(name):], "(") {
+		// Function-like macros are not supported, they are very unlikely to be used in a resource script.
+		return nil
+	}
+	toks, err := tokenizeRC(rest, file, line)
+	if err != nil {
+		return err
+	}
+	pp.defines[name] = toks
+	return nil
+}
+
+func (pp *rcPreprocessor) include(s string, file string, line int) error {
+	if len(s) < 2 || !(s[0] == '"' && s[len(s)-1] == '"' || s[0] == '<' && s[len(s)-1] == '>') {
+		return fmt.Errorf("%s:%d: invalid #include", file, line)
+	}
+	name := strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\\`, `\`), `\`, "/")
+	path := filepath.Join(filepath.Dir(file), name)
+	if _, err := os.Stat(path); err != nil {
+		if rcSystemHeaders[strings.ToLower(filepath.Base(name))] {
+			return nil
+		}
+		return fmt.Errorf("%s:%d: %v", file, line, err)
+	}
+
+	if pp.depth >= rcMaxIncludeDepth {
+		return fmt.Errorf("%s:%d: %s", file, line, errRCIncludeDepth)
+	}
+	pp.depth++
+	defer func() { pp.depth-- }()
+
+	ext := strings.ToLower(filepath.Ext(path))
+	return pp.processFile(path, ext == ".h" || ext == ".c")
+}
+
+func (pp *rcPreprocessor) condition(directive string, s string, file string, line int) (bool, error) {
+	switch directive {
+	case "ifdef":
+		_, ok := pp.defines[strings.TrimSpace(s)]
+		return ok, nil
+	case "ifndef":
+		_, ok := pp.defines[strings.TrimSpace(s)]
+		return !ok, nil
+	}
+
+	toks, err := tokenizeRC(s, file, line)
+	if err != nil {
+		return false, err
+	}
+
+	// "defined" must be evaluated before macro expansion
+	var replaced []rcToken
+	for i := 0; i < len(toks); i++ {
+		if !toks[i].is("defined") {
+			replaced = append(replaced, toks[i])
+			continue
+		}
+		var name string
+		switch {
+		case i+3 < len(toks) && toks[i+1].is("(") && toks[i+3].is(")"):
+			name = toks[i+2].text
+			i += 3
+		case i+1 < len(toks):
+			name = toks[i+1].text
+			i++
+		default:
+			return false, fmt.Errorf("%s:%d: invalid condition", file, line)
+		}
+		n := "0"
+		if _, ok := pp.defines[name]; ok {
+			n = "1"
+		}
+		replaced = append(replaced, rcToken{kind: rcNumber, text: n, file: file, line: line})
+	}
+
+	// Remaining identifiers are undefined, so they are zero
+	toks = pp.expand(replaced, nil)
+	for i := range toks {
+		if toks[i].kind == rcWord {
+			toks[i] = rcToken{kind: rcNumber, text: "0", file: file, line: line}
+		}
+	}
+
+	st := &rcStream{toks: toks}
+	v, err := st.expr()
+	if err == nil && st.peek() != nil {
+		err = errors.New("invalid condition")
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s:%d: %v", file, line, err)
+	}
+	return v.n != 0, nil
+}
+
+// expand replaces macros recursively.
+func (pp *rcPreprocessor) expand(toks []rcToken, hidden map[string]bool) []rcToken {
+	var out []rcToken
+	for _, t := range toks {
+		def, ok := pp.defines[t.text]
+		if t.kind != rcWord || !ok || hidden[t.text] {
+			out = append(out, t)
+			continue
+		}
+		h := map[string]bool{t.text: true}
+		for k := range hidden {
+			h[k] = true
+		}
+		exp := make([]rcToken, len(def))
+		for i := range def {
+			exp[i] = def[i]
+			exp[i].file, exp[i].line = t.file, t.line
+		}
+		out = append(out, pp.expand(exp, h)...)
+	}
+	return out
+}
+
+// decodeRC returns the text of a resource script, which may be encoded in UTF-16.
+func decodeRC(b []byte) string {
+	switch {
+	case len(b) >= 2 && b[0] == 0xFF && b[1] == 0xFE:
+		u := make([]uint16, (len(b)-2)/2)
+		for i := range u {
+			u[i] = uint16(b[2+i*2]) | uint16(b[3+i*2])<<8
+		}
+		return string(utf16.Decode(u))
+	case len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF:
+		u := make([]uint16, (len(b)-2)/2)
+		for i := range u {
+			u[i] = uint16(b[2+i*2])<<8 | uint16(b[3+i*2])
+		}
+		return string(utf16.Decode(u))
+	case len(b) >= 3 && b[0] == 0xEF && b[1] == 0xBB && b[2] == 0xBF:
+		return string(b[3:])
+	case !utf8.Valid(b):
+		// Most likely an ANSI code page, Latin-1 is the closest guess.
+		r := make([]rune, len(b))
+		for i := range b {
+			r[i] = rune(b[i])
+		}
+		return string(r)
+	}
+	return string(b)
+}
+
+// stripComments replaces C and C++ comments with spaces, keeping line breaks.
+func stripComments(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' && s[j] != '\n' {
+				if s[j] == '\\' && j+1 < len(s) && s[j+1] != '\n' {
+					j++
+				}
+				j++
+			}
+			if j < len(s) && s[j] == '"' {
+				j++
+			}
+			sb.WriteString(s[i:j])
+			i = j - 1
+		case c == '/' && i+1 < len(s) && s[i+1] == '/':
+			for i+1 < len(s) && s[i+1] != '\n' {
+				i++
+			}
+			sb.WriteByte(' ')
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			i += 2
+			for i < len(s) && !(s[i] == '*' && i+1 < len(s) && s[i+1] == '/') {
+				if s[i] == '\n' {
+					sb.WriteByte('\n')
+				}
+				i++
+			}
+			i++
+			sb.WriteByte(' ')
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func isRCWordRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r >= 0x80
+}
+
+// Words may also contain dots and backslashes, because file names don't have to be quoted.
+func isRCWordByte(c byte) bool {
+	return isRCWordRune(rune(c)) || c == '.' || c == '\\'
+}
+
+var rcPunctuators = []string{"&&", "||", "==", "!=", "<=", ">=", "<<", ">>"}
+
+func tokenizeRC(line string, file string, lineNo int) ([]rcToken, error) {
+	var toks []rcToken
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case c == '"' || (c == 'L' || c == 'l') && i+1 < len(line) && line[i+1] == '"':
+			wide := c != '"'
+			if wide {
+				i++
+			}
+			j := i + 1
+			for {
+				if j >= len(line) {
+					return nil, fmt.Errorf("%s:%d: %s", file, lineNo, errRCUnterminatedString)
+				}
+				if line[j] == '\\' {
+					j += 2
+					continue
+				}
+				if line[j] == '"' {
+					if j+1 < len(line) && line[j+1] == '"' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			toks = append(toks, rcToken{kind: rcString, text: line[i+1 : j], wide: wide, file: file, line: lineNo})
+			i = j + 1
+		case isRCWordByte(c):
+			j := i
+			for j < len(line) && isRCWordByte(line[j]) {
+				j++
+			}
+			kind := rcWord
+			if c >= '0' && c <= '9' {
+				kind = rcNumber
+			}
+			toks = append(toks, rcToken{kind: kind, text: line[i:j], file: file, line: lineNo})
+			i = j
+		default:
+			p := line[i : i+1]
+			for _, s := range rcPunctuators {
+				if strings.HasPrefix(line[i:], s) {
+					p = s
+				}
+			}
+			toks = append(toks, rcToken{kind: rcPunct, text: p, file: file, line: lineNo})
+			i += len(p)
+		}
+	}
+	return toks, nil
+}
+
+// unescapeRC processes escape sequences in a string token.
+//
+// In a narrow string, numeric escape sequences are bytes.
+// In a wide string, they are UTF-16 code units.
+func unescapeRC(s string, wide bool) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '"' && i+1 < len(s) && s[i+1] == '"' {
+			b = append(b, '"')
+			i++
+			continue
+		}
+		if c != '\\' || i+1 == len(s) {
+			b = append(b, c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'a':
+			b = append(b, '\a')
+		case '\\', '"', '\'':
+			b = append(b, s[i])
+		case 'x', 'X':
+			max := 2
+			if wide {
+				max = 4
+			}
+			j := i + 1
+			for j < len(s) && j-i-1 < max && strings.IndexByte("0123456789abcdefABCDEF", s[j]) >= 0 {
+				j++
+			}
+			n, _ := strconv.ParseUint(s[i+1:j], 16, 16)
+			b = appendEscaped(b, n, wide)
+			i = j - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(s) && j-i < 3 && s[j] >= '0' && s[j] <= '7' {
+				j++
+			}
+			n, _ := strconv.ParseUint(s[i:j], 8, 16)
+			b = appendEscaped(b, n, wide)
+			i = j - 1
+		default:
+			b = append(b, '\\', s[i])
+		}
+	}
+	return string(b)
+}
+
+func appendEscaped(b []byte, n uint64, wide bool) []byte {
+	if wide {
+		return append(b, string(rune(n))...)
+	}
+	return append(b, byte(n))
+}
+
+// rcStream is a list of tokens being parsed.
+type rcStream struct {
+	toks []rcToken
+	pos  int
+}
+
+func (s *rcStream) peek() *rcToken {
+	if s.pos >= len(s.toks) {
+		return nil
+	}
+	return &s.toks[s.pos]
+}
+
+func (s *rcStream) next() *rcToken {
+	t := s.peek()
+	if t != nil {
+		s.pos++
+	}
+	return t
+}
+
+// accept skips the next token if it is the given punctuator or keyword.
+func (s *rcStream) accept(text string) bool {
+	if s.peek().is(text) {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+func (s *rcStream) expect(text string) error {
+	if s.accept(text) {
+		return nil
+	}
+	if t := s.peek(); t != nil {
+		return fmt.Errorf("expected %q, found %q", text, t.text)
+	}
+	return errors.New(errRCUnexpectedEnd)
+}
+
+// rcValue is the value of an expression.
+// Numbers with an "L" suffix are long, they take 4 bytes in raw data.
+type rcValue struct {
+	n    int64
+	long bool
+}
+
+var rcBinaryOps = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, ">": 7, "<=": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+}
+
+// expr parses an integer expression.
+func (s *rcStream) expr() (rcValue, error) {
+	return s.binary(1)
+}
+
+func (s *rcStream) binary(minPrec int) (rcValue, error) {
+	lhs, err := s.unary()
+	if err != nil {
+		return lhs, err
+	}
+	for {
+		t := s.peek()
+		if t == nil || t.kind != rcPunct {
+			return lhs, nil
+		}
+		prec, ok := rcBinaryOps[t.text]
+		if !ok || prec < minPrec {
+			return lhs, nil
+		}
+		s.pos++
+		rhs, err := s.binary(prec + 1)
+		if err != nil {
+			return lhs, err
+		}
+		lhs, err = applyBinary(t.text, lhs, rhs)
+		if err != nil {
+			return lhs, err
+		}
+	}
+}
+
+func applyBinary(op string, a, b rcValue) (rcValue, error) {
+	v := rcValue{long: a.long || b.long}
+	bool2int := func(b bool) int64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	switch op {
+	case "||":
+		v.n = bool2int(a.n != 0 || b.n != 0)
+	case "&&":
+		v.n = bool2int(a.n != 0 && b.n != 0)
+	case "|":
+		v.n = a.n | b.n
+	case "^":
+		v.n = a.n ^ b.n
+	case "&":
+		v.n = a.n & b.n
+	case "==":
+		v.n = bool2int(a.n == b.n)
+	case "!=":
+		v.n = bool2int(a.n != b.n)
+	case "<":
+		v.n = bool2int(a.n < b.n)
+	case ">":
+		v.n = bool2int(a.n > b.n)
+	case "<=":
+		v.n = bool2int(a.n <= b.n)
+	case ">=":
+		v.n = bool2int(a.n >= b.n)
+	case "<<":
+		v.n = a.n << uint64(b.n)
+	case ">>":
+		v.n = a.n >> uint64(b.n)
+	case "+":
+		v.n = a.n + b.n
+	case "-":
+		v.n = a.n - b.n
+	case "*":
+		v.n = a.n * b.n
+	case "/", "%":
+		if b.n == 0 {
+			return v, errors.New(errRCDivisionByZero)
+		}
+		if op == "/" {
+			v.n = a.n / b.n
+		} else {
+			v.n = a.n % b.n
+		}
+	}
+	return v, nil
+}
+
+func (s *rcStream) unary() (rcValue, error) {
+	t := s.next()
+	if t == nil {
+		return rcValue{}, errors.New(errRCUnexpectedEnd)
+	}
+	switch {
+	case t.kind == rcNumber:
+		return parseRCNumber(t.text)
+	case t.is("("):
+		v, err := s.expr()
+		if err != nil {
+			return v, err
+		}
+		return v, s.expect(")")
+	case t.is("-"), t.is("+"), t.is("~"), t.is("!"):
+		v, err := s.unary()
+		switch t.text {
+		case "-":
+			v.n = -v.n
+		case "~":
+			v.n = ^v.n
+		case "!":
+			if v.n == 0 {
+				v.n = 1
+			} else {
+				v.n = 0
+			}
+		}
+		return v, err
+	case t.kind == rcWord:
+		return rcValue{}, fmt.Errorf("undefined symbol %q", t.text)
+	}
+	return rcValue{}, fmt.Errorf("unexpected %q", t.text)
+}
+
+func parseRCNumber(s string) (rcValue, error) {
+	v := rcValue{}
+	t := strings.TrimRight(s, "uUlL")
+	v.long = strings.ContainsAny(s[len(t):], "lL")
+	n, err := strconv.ParseUint(t, 0, 64)
+	if err != nil {
+		n, err = strconv.ParseUint(t, 10, 64)
+	}
+	if err != nil {
+		return v, fmt.Errorf("invalid number %q", s)
+	}
+	v.n = int64(n)
+	return v, nil
+}
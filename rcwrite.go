@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tc-hib/winres"
+	"github.com/tc-hib/winres/version"
+)
+
+// Resource types that have a keyword in resource scripts.
+// Other standard types are written as numbers, so the script doesn't depend on windows.h.
+var rcTypeKeywords = map[string]string{
+	"RT_GROUP_ICON":   "ICON",
+	"RT_GROUP_CURSOR": "CURSOR",
+	"RT_BITMAP":       "BITMAP",
+	"RT_FONT":         "FONT",
+	"RT_MESSAGETABLE": "MESSAGETABLE",
+	"RT_ANICURSOR":    "ANICURSOR",
+	"RT_ANIICON":      "ANIICON",
+	"RT_HTML":         "HTML",
+	"RT_RCDATA":       "RCDATA",
+}
+
+var rcIdentifier = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+var rcKeywords = map[string]bool{
+	"LANGUAGE":        true,
+	"STRINGTABLE":     true,
+	"VERSION":         true,
+	"CHARACTERISTICS": true,
+	"BEGIN":           true,
+	"END":             true,
+}
+
+// writeRC writes a resource script from a resource set definition.
+//
+// Resources must be files, except string tables and version info.
+func writeRC(name string, res jsonDef) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "// Generated by go-winres")
+	fmt.Fprintln(w, "#pragma code_page(65001)")
+
+	lang := -1
+	for _, tid := range sortedTypes(res) {
+		// rc.exe makes the font directory from FONT statements
+		if tid == "RT_FONTDIR" {
+			continue
+		}
+		typ := rcTypeKeywords[tid]
+		if typ == "" {
+			typ = rcType(tid)
+		}
+
+		for _, r := range sortedRes(res[tid]) {
+			if isStringTable(tid, r.id) {
+				writeRCStringTable(w, r.id, r.langs)
+				continue
+			}
+			if strings.ToUpper(r.id) != r.id {
+				log.Printf("[%s][%s] rc.exe converts resource names to upper case", tid, r.id)
+			}
+			for _, l := range sortedLang(r.langs) {
+				langID, err := strconv.ParseUint(l.id, 16, 16)
+				if err != nil {
+					return err
+				}
+				if int(langID) != lang {
+					lang = int(langID)
+					fmt.Fprintf(w, "\nLANGUAGE 0x%02X, 0x%02X\n", langID&0x3FF, langID>>10)
+				}
+
+				switch data := l.data.(type) {
+				case string:
+					fmt.Fprintf(w, "%s %s \"%s\"\n", rcName(r.id), typ, strings.ReplaceAll(data, `\`, `\\`))
+				case *version.Info:
+					writeRCVersionInfo(w, rcName(r.id), data)
+				default:
+					log.Printf("[%s][%s][%s] can't be written in a resource script", tid, r.id, l.id)
+				}
+			}
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// sortedTypes sorts types by ID, then by name.
+func sortedTypes(res jsonDef) []string {
+	var types []string
+	for t := range res {
+		types = append(types, t)
+	}
+	id := func(t string) int {
+		if n, ok := typeIDFromString[t]; ok {
+			return int(n)
+		}
+		if n, ok := stringToIdentifier(t).(winres.ID); ok {
+			return int(n)
+		}
+		return 0x10000
+	}
+	sort.Slice(types, func(i, j int) bool {
+		a, b := id(types[i]), id(types[j])
+		if a != b {
+			return a < b
+		}
+		return types[i] < types[j]
+	})
+	return types
+}
+
+func rcType(s string) string {
+	if n, ok := typeIDFromString[s]; ok {
+		return strconv.Itoa(int(n))
+	}
+	return rcName(s)
+}
+
+// rcName converts a resource identifier.
+// Names that could be mistaken for a keyword or a constant are quoted.
+func rcName(s string) string {
+	if n, ok := stringToIdentifier(s).(winres.ID); ok {
+		return strconv.Itoa(int(n))
+	}
+	_, isDefine := rcBuiltinDefines[s]
+	if rcIdentifier.MatchString(s) && !isDefine && !rcKeywords[s] {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func writeRCStringTable(w *bufio.Writer, l string, strs map[string]interface{}) {
+	langID, _ := strconv.ParseUint(l, 16, 16)
+	var ids []int
+	for id := range strs {
+		n, err := strconv.Atoi(id)
+		if err == nil {
+			ids = append(ids, n)
+		}
+	}
+	sort.Ints(ids)
+
+	fmt.Fprintf(w, "\nSTRINGTABLE LANGUAGE 0x%02X, 0x%02X\nBEGIN\n", langID&0x3FF, langID>>10)
+	for _, id := range ids {
+		s, _ := strs[strconv.Itoa(id)].(string)
+		fmt.Fprintf(w, "    %d, %s\n", id, quoteRC(s))
+	}
+	fmt.Fprintln(w, "END")
+}
+
+func writeRCVersionInfo(w *bufio.Writer, name string, vi *version.Info) {
+	var flags int
+	if vi.Flags.Debug {
+		flags |= vsFFDebug
+	}
+	if vi.Flags.Prerelease {
+		flags |= vsFFPrerelease
+	}
+	if vi.Flags.Patched {
+		flags |= vsFFPatched
+	}
+	if vi.Flags.PrivateBuild {
+		flags |= vsFFPrivateBuild
+	}
+	if vi.Flags.SpecialBuild {
+		flags |= vsFFSpecialBuild
+	}
+	fileType := 0
+	switch vi.Type {
+	case version.App:
+		fileType = vftApp
+	case version.DLL:
+		fileType = vftDLL
+	}
+
+	if !vi.Timestamp.IsZero() {
+		log.Printf("[%s] the timestamp of the version info can't be written in a resource script", name)
+	}
+
+	v, p := vi.FileVersion, vi.ProductVersion
+	fmt.Fprintf(w, "%s VERSIONINFO\n", name)
+	fmt.Fprintf(w, "FILEVERSION %d,%d,%d,%d\n", v[0], v[1], v[2], v[3])
+	fmt.Fprintf(w, "PRODUCTVERSION %d,%d,%d,%d\n", p[0], p[1], p[2], p[3])
+	fmt.Fprintln(w, "FILEFLAGSMASK 0x3F")
+	fmt.Fprintf(w, "FILEFLAGS 0x%X\n", flags)
+	fmt.Fprintln(w, "FILEOS 0x40004")
+	fmt.Fprintf(w, "FILETYPE 0x%X\n", fileType)
+	fmt.Fprintln(w, "FILESUBTYPE 0x0")
+	fmt.Fprintln(w, "BEGIN")
+
+	table := vi.Table()
+	var langs []int
+	for l := range table {
+		langs = append(langs, int(l))
+	}
+	sort.Ints(langs)
+
+	fmt.Fprintln(w, "    BLOCK \"StringFileInfo\"")
+	fmt.Fprintln(w, "    BEGIN")
+	for _, l := range langs {
+		st := table[uint16(l)]
+		var keys []string
+		for k := range *st {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(w, "        BLOCK \"%04X04B0\"\n", l)
+		fmt.Fprintln(w, "        BEGIN")
+		for _, k := range keys {
+			fmt.Fprintf(w, "            VALUE \"%s\", %s\n", strings.ReplaceAll(k, `"`, `""`), quoteRC((*st)[k]))
+		}
+		fmt.Fprintln(w, "        END")
+	}
+	fmt.Fprintln(w, "    END")
+
+	fmt.Fprintln(w, "    BLOCK \"VarFileInfo\"")
+	fmt.Fprintln(w, "    BEGIN")
+	fmt.Fprint(w, "        VALUE \"Translation\"")
+	for _, l := range langs {
+		fmt.Fprintf(w, ", 0x%04X, 1200", l)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    END")
+	fmt.Fprintln(w, "END")
+}
+
+// quoteRC writes a wide string literal.
+func quoteRC(s string) string {
+	var sb strings.Builder
+	sb.WriteString(`L"`)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			sb.WriteString(`""`)
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20 || r == 0x7F:
+			fmt.Fprintf(&sb, `\x%04X`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tc-hib/winres/version"
+)
+
+func Test_writeRC(t *testing.T) {
+	vi := &version.Info{
+		FileVersion:    [4]uint16{1, 2, 3, 4},
+		ProductVersion: [4]uint16{5, 6, 7, 8},
+		Type:           version.DLL,
+	}
+	vi.Flags.Patched = true
+	vi.Set(0x409, "FileDescription", `A "quoted" description`)
+	vi.Set(0x40C, "FileDescription", "Une description")
+
+	res := jsonDef{
+		"RT_GROUP_ICON": {
+			"APP":     {"0000": "APP_0000.ico"},
+			"#42":     {"0409": "#42_0409.ico", "040C": `dir\#42_040C.ico`},
+			"lower":   {"0000": "lower.ico"},
+			"BEGIN":   {"0000": "begin.ico"},
+			"VFT_APP": {"0000": "vft_app.ico"},
+		},
+		"RT_STRING": {
+			"0409": {"1": "Hello\r\n\t\"World\" \\ \x01", "1000": "é"},
+		},
+		"RT_VERSION": {
+			"#1": {"0409": vi},
+		},
+		"#24": {
+			"#1": {"0409": "app.manifest"},
+		},
+		"MY TYPE": {
+			"#1": {"0000": "data.bin"},
+		},
+	}
+
+	name := filepath.Join(t.TempDir(), "winres.rc")
+	err := writeRC(name, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := ioutil.ReadFile(name)
+	if !strings.Contains(string(b), "\nAPP ICON \"APP_0000.ico\"\n") {
+		t.Errorf("unexpected script:\n%s", b)
+	}
+
+	res2, err := loadRC(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vi2 := res2["RT_VERSION"]["#1"]["0409"].(*version.Info)
+	if !reflect.DeepEqual(vi2, vi) {
+		t.Errorf("version info = %+v, want %+v", vi2, vi)
+	}
+	delete(res2, "RT_VERSION")
+	delete(res, "RT_VERSION")
+
+	// rc.exe converts names to upper case
+	res["RT_GROUP_ICON"]["LOWER"] = res["RT_GROUP_ICON"]["lower"]
+	delete(res["RT_GROUP_ICON"], "lower")
+	res["RT_GROUP_ICON"]["#42"]["040C"] = "dir/#42_040C.ico"
+
+	if !reflect.DeepEqual(res2, res) {
+		t.Errorf("loadRC() = %v, want %v", res2, res)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errInvalidRES = "invalid .res file"
+)
+
+// Memory flags are obsolete, but rc.exe still writes MOVEABLE|PURE|DISCARDABLE
+const resMemoryFlags = 0x1030
+
+// A .res file starts with an empty entry, which tells it from a 16-bit .res file.
+var resSignature = []byte{
+	0, 0, 0, 0, 0x20, 0, 0, 0,
+	0xFF, 0xFF, 0, 0, 0xFF, 0xFF, 0, 0,
+}
+
+type resHeaderTail struct {
+	DataVersion     uint32
+	MemoryFlags     uint16
+	LanguageID      uint16
+	Version         uint32
+	Characteristics uint32
+}
+
+// isRES tells if data looks like a 32-bit .res file.
+func isRES(data []byte) bool {
+	return len(data) >= 32 && bytes.Equal(data[:len(resSignature)], resSignature)
+}
+
+// readRES adds the resources of a 32-bit .res file to a resource set.
+//
+// Resources are added as they are, so icons and cursors keep their IDs.
+//
+// https://docs.microsoft.com/en-us/windows/win32/menurc/resourceheader
+func readRES(rs *winres.ResourceSet, data []byte) error {
+	if !isRES(data) {
+		return errors.New(errInvalidRES)
+	}
+
+	pos := 0
+	for pos+8 <= len(data) {
+		dataSize := int(binary.LittleEndian.Uint32(data[pos:]))
+		headerSize := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		if headerSize < 8 || pos+headerSize+dataSize > len(data) {
+			return errors.New(errInvalidRES)
+		}
+
+		p := pos + 8
+		typeID, err := readResIdentifier(data, &p)
+		if err != nil {
+			return err
+		}
+		resID, err := readResIdentifier(data, &p)
+		if err != nil {
+			return err
+		}
+		p = (p + 3) &^ 3
+		if p+16 > pos+headerSize {
+			return errors.New(errInvalidRES)
+		}
+		langID := binary.LittleEndian.Uint16(data[p+6:])
+
+		// The first entry is empty
+		if typeID != winres.ID(0) {
+			content := data[pos+headerSize : pos+headerSize+dataSize]
+			err = rs.Set(typeID, resID, langID, append([]byte{}, content...))
+			if err != nil {
+				return err
+			}
+		}
+
+		pos = (pos + headerSize + dataSize + 3) &^ 3
+	}
+
+	return nil
+}
+
+func readResIdentifier(data []byte, pos *int) (winres.Identifier, error) {
+	if *pos+4 > len(data) {
+		return nil, errors.New(errInvalidRES)
+	}
+	if binary.LittleEndian.Uint16(data[*pos:]) == 0xFFFF {
+		id := winres.ID(binary.LittleEndian.Uint16(data[*pos+2:]))
+		*pos += 4
+		return id, nil
+	}
+	s, err := readSZ(data, pos)
+	if err != nil {
+		return nil, errors.New(errInvalidRES)
+	}
+	return winres.Name(s), nil
+}
+
+// writeRES writes a resource set as a 32-bit .res file, for rc.exe, cvtres.exe, or windres.
+func writeRES(w io.Writer, rs *winres.ResourceSet) error {
+	buf := &bytes.Buffer{}
+	writeRESEntry(buf, winres.ID(0), winres.ID(0), resHeaderTail{}, nil)
+
+	rs.Walk(func(typeID, resID winres.Identifier, langID uint16, data []byte) bool {
+		writeRESEntry(buf, typeID, resID, resHeaderTail{MemoryFlags: resMemoryFlags, LanguageID: langID}, data)
+		return true
+	})
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeRESEntry(buf *bytes.Buffer, typeID, resID winres.Identifier, tail resHeaderTail, data []byte) {
+	hdr := &bytes.Buffer{}
+	writeResIdentifier(hdr, typeID)
+	writeResIdentifier(hdr, resID)
+	for (hdr.Len()+8)%4 != 0 {
+		hdr.WriteByte(0)
+	}
+	binary.Write(hdr, binary.LittleEndian, tail)
+
+	binary.Write(buf, binary.LittleEndian, [2]uint32{uint32(len(data)), uint32(hdr.Len() + 8)})
+	buf.Write(hdr.Bytes())
+	buf.Write(data)
+	padDWORD(buf)
+}
+
+func writeResIdentifier(buf *bytes.Buffer, id winres.Identifier) {
+	switch id := id.(type) {
+	case winres.ID:
+		binary.Write(buf, binary.LittleEndian, [2]uint16{0xFFFF, uint16(id)})
+	case winres.Name:
+		writeSZ(buf, string(id))
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_writeRES_readRES(t *testing.T) {
+	f, err := os.Open(filepath.Join("_testdata", "rh.exe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rs, err := winres.LoadFromEXE(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = writeRES(buf, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isRES(buf.Bytes()) {
+		t.Fatal("isRES returned false")
+	}
+
+	rs2 := &winres.ResourceSet{}
+	err = readRES(rs2, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	rs.Walk(func(typeID, resID winres.Identifier, langID uint16, data []byte) bool {
+		count++
+		if !bytes.Equal(rs2.Get(typeID, resID, langID), data) {
+			t.Errorf("[%v][%v][%04X] differs", typeID, resID, langID)
+		}
+		return true
+	})
+	rs2.Walk(func(typeID, resID winres.Identifier, langID uint16, data []byte) bool {
+		count--
+		return true
+	})
+	if count != 0 {
+		t.Errorf("wrong number of resources (%d)", count)
+	}
+}
+
+func Test_readRES_Errors(t *testing.T) {
+	valid := &bytes.Buffer{}
+	rs := &winres.ResourceSet{}
+	rs.Set(winres.Name("NAME"), winres.ID(1), 0x409, []byte{1, 2, 3})
+	writeRES(valid, rs)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not res", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xFF\xFF\x00\x00\xB8\x00\x00\x00\x00\x00\x00\x00\x40\x00\x00\x00\x00\x00\x00\x00")},
+		{"truncated data", valid.Bytes()[:valid.Len()-4]},
+		{"truncated header", valid.Bytes()[:40]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := readRES(&winres.ResourceSet{}, tt.data)
+			if err == nil || err.Error() != errInvalidRES {
+				t.Errorf("readRES() error = %v, want %q", err, errInvalidRES)
+			}
+		})
+	}
+}
+
+func Test_importResources_Include(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	rs := &winres.ResourceSet{}
+	rs.Set(winres.RT_RCDATA, winres.Name("DATA"), 0x409, []byte("data"))
+	rs.Set(winres.RT_RCDATA, winres.Name("REPLACED"), 0x409, []byte("old"))
+	buf := &bytes.Buffer{}
+	writeRES(buf, rs)
+	err := ioutil.WriteFile(filepath.Join(tmpDir, "legacy.res"), buf.Bytes(), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "new.txt"), []byte("new"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := `{
+  "$include": ["legacy.res"],
+  "RT_RCDATA": {"REPLACED": {"0409": "new.txt"}}
+}`
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "winres.json"), []byte(j), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs = &winres.ResourceSet{}
+	err = importResources(rs, filepath.Join(tmpDir, "winres.json"), importOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rs.Get(winres.RT_RCDATA, winres.Name("DATA"), 0x409)) != "data" {
+		t.Error("included resource is missing")
+	}
+	if string(rs.Get(winres.RT_RCDATA, winres.Name("REPLACED"), 0x409)) != "new" {
+		t.Error("included resource should be replaced")
+	}
+
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "bad.json"), []byte(`{"$include": "new.txt"}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = importResources(&winres.ResourceSet{}, filepath.Join(tmpDir, "bad.json"), importOptions{})
+	if err == nil {
+		t.Error("expected an error when including a file that is not a .res file")
+	}
+
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "bad.json"), []byte(`{"$include": 42}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = importResources(&winres.ResourceSet{}, filepath.Join(tmpDir, "bad.json"), importOptions{})
+	if err == nil || err.Error() != errInvalidInclude {
+		t.Errorf("importResources() error = %v, want %q", err, errInvalidInclude)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/png"
 	"io/ioutil"
 	"log"
 	"os"
@@ -20,11 +21,14 @@ import (
 )
 
 const (
-	errInvalidSet    = "invalid resource set definition"
-	errInvalidCursor = "invalid cursor definition"
-	errInvalidIcon   = "invalid icon definition"
+	errInvalidSet     = "invalid resource set definition"
+	errInvalidCursor  = "invalid cursor definition"
+	errInvalidIcon    = "invalid icon definition"
+	errInvalidInclude = "invalid $include directive"
 )
 
+const includeKey = "$include"
+
 type jsonDef map[string]map[string]map[string]interface{}
 
 var typeIDToString = map[winres.ID]string{
@@ -73,9 +77,34 @@ var typeIDFromString = map[string]winres.ID{
 	"RT_MANIFEST":     winres.RT_MANIFEST,
 }
 
-func exportResources(dir string, rs *winres.ResourceSet, manifestInJSON bool) {
+// exportOptions tells how resources are extracted.
+type exportOptions struct {
+	manifestInJSON bool
+	format         string
+	// tree extracts all named RT_HTML and RT_RCDATA resources as a directory tree,
+	// not only the ones that have a slash in their name
+	tree bool
+	// inline is the maximum size of data that is written in the json file instead of a file
+	inline int
+	// pngImages extracts each image of icons and cursors to a png file, instead of ico and cur files
+	pngImages bool
+}
+
+// importOptions tells how resources are imported.
+type importOptions struct {
+	// strict rejects comments and trailing commas in json files,
+	// misspelled resource types, and unknown keys in the manifest and the version info
+	strict bool
+}
+
+// exportResources writes the resources of a set to files, and a definition in the given format.
+//
+// A resource script can't describe json structures, so only string tables and version info
+// are decoded in that case.
+func exportResources(dir string, rs *winres.ResourceSet, opt exportOptions) {
 	res := jsonDef{}
-	jsonName := filepath.Join(dir, "winres.json")
+	decode := opt.format != formatRC
+	manifestInJSON := opt.manifestInJSON && decode
 
 	rs.Walk(func(typeID, resID winres.Identifier, langID uint16, data []byte) bool {
 		switch typeID {
@@ -89,6 +118,42 @@ func exportResources(dir string, rs *winres.ResourceSet, manifestInJSON bool) {
 		if res[t] == nil {
 			res[t] = make(map[string]map[string]interface{})
 		}
+
+		if p := treePath(typeID, resID, opt.tree); p != "" {
+			// Files of a directory tree go to a directory named after their type and language
+			td := t + "_" + l
+			err := saveTreeFile(filepath.Join(dir, td, filepath.FromSlash(p)), data)
+			if err != nil {
+				log.Printf("[%s][%s][%s] %v", t, r, l, err)
+				return true
+			}
+			if !decode {
+				if res[t][r] == nil {
+					res[t][r] = make(map[string]interface{})
+				}
+				res[t][r][l] = td + "/" + p
+				return true
+			}
+			if res[t][treeSuffix] == nil {
+				res[t][treeSuffix] = make(map[string]interface{})
+			}
+			res[t][treeSuffix][l] = td
+			return true
+		}
+
+		if typeID == winres.RT_STRING {
+			// String tables are listed by language, then by string ID, skipping the block level.
+			if strs, err := readStringBlock(resID, data); err == nil {
+				if res[t][l] == nil {
+					res[t][l] = make(map[string]interface{})
+				}
+				for id, s := range strs {
+					res[t][l][strconv.Itoa(int(id))] = s
+				}
+				return true
+			}
+		}
+
 		if res[t][r] == nil {
 			res[t][r] = make(map[string]interface{})
 		}
@@ -99,6 +164,15 @@ func exportResources(dir string, rs *winres.ResourceSet, manifestInJSON bool) {
 
 		switch typeID {
 		case winres.RT_GROUP_ICON:
+			if opt.pngImages {
+				files, err := saveIconImages(filename, rs, resID, langID)
+				if err == nil {
+					res[t][r][l] = files
+					return true
+				}
+				// The icon is extracted as is, so that nothing is lost
+				printError(fmt.Errorf("%v, writing %s instead", err, filepath.Base(filename)))
+			}
 			err := saveIcon(filename, rs, resID, langID)
 			if err != nil {
 				printError(err)
@@ -107,6 +181,14 @@ func exportResources(dir string, rs *winres.ResourceSet, manifestInJSON bool) {
 			res[t][r][l] = filepath.Base(filename)
 			return true
 		case winres.RT_GROUP_CURSOR:
+			if opt.pngImages {
+				images, err := saveCursorImages(filename, rs, resID, langID)
+				if err == nil {
+					res[t][r][l] = images
+					return true
+				}
+				printError(fmt.Errorf("%v, writing %s instead", err, filepath.Base(filename)))
+			}
 			err := saveCursor(filename, rs, resID, langID)
 			if err != nil {
 				printError(err)
@@ -122,6 +204,30 @@ func exportResources(dir string, rs *winres.ResourceSet, manifestInJSON bool) {
 			}
 			res[t][r][l] = filepath.Base(filename)
 			return true
+		case winres.RT_MESSAGETABLE:
+			messages, err := readMessageTable(data)
+			if err == nil && decode {
+				res[t][r][l] = messages
+				return true
+			}
+		case winres.RT_ACCELERATOR:
+			accels, err := readAccelerators(data)
+			if err == nil && decode {
+				res[t][r][l] = accels
+				return true
+			}
+		case winres.RT_MENU:
+			items, err := readMenu(data)
+			if err == nil && decode {
+				res[t][r][l] = items
+				return true
+			}
+		case winres.RT_DIALOG:
+			dlg, err := readDialog(data)
+			if err == nil && decode {
+				res[t][r][l] = dlg
+				return true
+			}
 		case winres.RT_VERSION:
 			vi, err := version.FromBytes(data)
 			if err != nil {
@@ -141,6 +247,10 @@ func exportResources(dir string, rs *winres.ResourceSet, manifestInJSON bool) {
 				return true
 			}
 		}
+		if decode && opt.inline > 0 && len(data) <= opt.inline && isInlinable(typeID) {
+			res[t][r][l] = inlineValue(data)
+			return true
+		}
 		err := ioutil.WriteFile(filename, data, 0666)
 		if err != nil {
 			printError(err)
@@ -150,18 +260,39 @@ func exportResources(dir string, rs *winres.ResourceSet, manifestInJSON bool) {
 		return true
 	})
 
+	if opt.format == formatRC {
+		err := writeRC(filepath.Join(dir, "winres.rc"), res)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
 	b, err := json.MarshalIndent(res, "", "  ")
 	if err != nil {
 		log.Println(err)
 		return
 	}
+	b, err = fromJSON(b, opt.format)
+	if err != nil {
+		log.Println(err)
+		return
+	}
 
-	err = ioutil.WriteFile(jsonName, b, 0666)
+	err = ioutil.WriteFile(filepath.Join(dir, "winres."+opt.format), b, 0666)
 	if err != nil {
 		log.Println(err)
 	}
 }
 
+func saveTreeFile(filename string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0666)
+}
+
 func saveIcon(filename string, rs *winres.ResourceSet, resID winres.Identifier, langID uint16) error {
 	icon, err := rs.GetIconTranslation(resID, langID)
 	if err != nil {
@@ -202,6 +333,86 @@ func saveCursor(filename string, rs *winres.ResourceSet, resID winres.Identifier
 	return f.Close()
 }
 
+// saveIconImages writes each image of an icon to a png file.
+// It returns the file names, as the array form of an icon definition.
+func saveIconImages(filename string, rs *winres.ResourceSet, resID winres.Identifier, langID uint16) ([]string, error) {
+	icon, err := rs.GetIconTranslation(resID, langID)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err = icon.SaveICO(buf); err != nil {
+		return nil, err
+	}
+	images, err := readIconImages(buf.Bytes(), false)
+	if err != nil {
+		return nil, err
+	}
+	return saveImages(filename, images)
+}
+
+// cursorImageDef is the definition of a cursor image, as read by loadCursorImage.
+type cursorImageDef struct {
+	Image string `json:"image"`
+	X     uint16 `json:"x"`
+	Y     uint16 `json:"y"`
+}
+
+// saveCursorImages writes each image of a cursor to a png file.
+// It returns the array form of a cursor definition.
+func saveCursorImages(filename string, rs *winres.ResourceSet, resID winres.Identifier, langID uint16) ([]cursorImageDef, error) {
+	cursor, err := rs.GetCursorTranslation(resID, langID)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err = cursor.SaveCUR(buf); err != nil {
+		return nil, err
+	}
+	images, err := readIconImages(buf.Bytes(), true)
+	if err != nil {
+		return nil, err
+	}
+	files, err := saveImages(filename, images)
+	if err != nil {
+		return nil, err
+	}
+	defs := make([]cursorImageDef, len(images))
+	for i := range images {
+		defs[i] = cursorImageDef{Image: files[i], X: images[i].hotSpot.X, Y: images[i].hotSpot.Y}
+	}
+	return defs, nil
+}
+
+// saveImages writes images to png files named after filename and their size, such as APP_0000_48x48.png.
+// It returns the base names of the files.
+func saveImages(filename string, images []iconImage) ([]string, error) {
+	var (
+		base  = strings.TrimSuffix(filename, filepath.Ext(filename))
+		files = make([]string, len(images))
+		used  = make(map[string]bool)
+	)
+	for i, img := range images {
+		sz := img.image.Bounds().Size()
+		name := fmt.Sprintf("%s_%dx%d.png", base, sz.X, sz.Y)
+		// An icon may have several images of the same size, with different color depths
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%dx%d_%d.png", base, sz.X, sz.Y, n)
+		}
+		used[name] = true
+
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img.image); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(name, buf.Bytes(), 0666); err != nil {
+			return nil, err
+		}
+		files[i] = filepath.Base(name)
+	}
+	return files, nil
+}
+
 func saveBitmap(filename string, dib []byte) error {
 	f, err := os.Create(filename)
 	if err != nil {
@@ -310,17 +521,17 @@ func idsFromStrings(t, r, l string) (winres.Identifier, winres.Identifier, uint1
 		typeID = stringToIdentifier(t)
 	}
 	if typeID == nil {
-		return nil, nil, 0, errors.New("invalid type identifier")
+		return nil, nil, 0, fmt.Errorf("invalid type identifier %q", t)
 	}
 
 	resID = stringToIdentifier(r)
 	if resID == nil {
-		return nil, nil, 0, errors.New("invalid resource identifier")
+		return nil, nil, 0, fmt.Errorf("invalid resource identifier %q", r)
 	}
 
 	n, err := strconv.ParseUint(l, 16, 16)
 	if err != nil {
-		return nil, nil, 0, errors.New("invalid language identifier")
+		return nil, nil, 0, fmt.Errorf("invalid language identifier %q", l)
 	}
 	langID = uint16(n)
 
@@ -359,6 +570,10 @@ func exportedName(first bool, data []byte, typeID, resID winres.Identifier, lang
 	case winres.RT_BITMAP:
 		ext = "bmp"
 		t = ""
+	case winres.RT_FONT:
+		if e, _, err := fontDirEntry(data); err == nil {
+			ext = e
+		}
 	case winres.RT_ANICURSOR:
 		ext = "ani"
 	case winres.RT_ANIICON:
@@ -384,108 +599,327 @@ func exportedName(first bool, data []byte, typeID, resID winres.Identifier, lang
 	return fmt.Sprintf("%s_%s_%s.%s", t, r, l, ext)
 }
 
-func importResources(rs *winres.ResourceSet, jsonName string) error {
-	dir := filepath.Dir(jsonName)
-	b, err := ioutil.ReadFile(jsonName)
-	if err != nil {
+// importResources imports a json file, a resource script (.rc) or a .res file into a resource set.
+func importResources(rs *winres.ResourceSet, name string, opt importOptions) error {
+	if isRESFile(name) {
+		return importRES(rs, name)
+	}
+
+	res, includes, err := loadDef(name, opt)
+	if res == nil {
 		return err
 	}
 
-	res := jsonDef{}
-	err = json.Unmarshal(b, &res)
+	var errs errorList
+	errs.add(err)
+	dir := filepath.Dir(name)
+	for _, inc := range includes {
+		if !isRESFile(inc) {
+			errs.add(fmt.Errorf("cannot include %q, only .res files can be included", inc))
+			continue
+		}
+		errs.add(importRES(rs, filepath.Join(dir, inc)))
+	}
+	if opt.strict {
+		errs.add(checkDef(res))
+	}
+	errs.add(importDef(rs, dir, res))
+	if len(errs) > 0 {
+		return locateErrors(name, errs.err())
+	}
+
+	if warnings, ok := locateErrors(name, lintIcons(rs, dir, res)).(errorList); ok {
+		for _, w := range warnings {
+			log.Println("warning:", w)
+		}
+	}
+	return nil
+}
+
+// loadDef reads a resource set definition, and the list of files it includes.
+//
+// Comments and trailing commas are accepted in json files, unless opt.strict is set.
+//
+// Top level keys that start with "$" are directives, not resource types.
+//
+// When some types have an invalid structure, they are left out, and their errors are returned
+// with the rest of the definition, so that other problems can be reported too.
+// The definition is nil when the file can't be read at all.
+func loadDef(name string, opt importOptions) (jsonDef, []string, error) {
+	format := defFormat(name)
+	if format == formatRC {
+		res, err := loadRC(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res, nil, nil
+	}
+
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if format != formatJSON || !opt.strict {
+		b, err = toJSON(b, format)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var m map[string]json.RawMessage
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		if format == formatJSON {
+			err = syntaxPosition(name, b, err)
+		}
+		return nil, nil, err
+	}
+
+	var (
+		res      = jsonDef{}
+		includes []string
+		errs     errorList
+	)
+	for k, v := range m {
+		if k == includeKey {
+			includes, err = parseIncludes(v)
+			errs.add(err)
+			continue
+		}
+		if strings.HasPrefix(k, "$") {
+			continue
+		}
+		t := make(map[string]map[string]interface{})
+		err = json.Unmarshal(v, &t)
+		if err != nil {
+			errs.add(atPath(errors.New(errInvalidSet), k))
+			continue
+		}
+		res[k] = t
+	}
+
+	return res, includes, locateErrors(name, errs.err())
+}
+
+// parseIncludes accepts a file name or a list of file names.
+func parseIncludes(b []byte) ([]string, error) {
+	var x interface{}
+	json.Unmarshal(b, &x)
+	switch x := x.(type) {
+	case string:
+		return []string{x}, nil
+	case []interface{}:
+		var includes []string
+		for _, inc := range x {
+			s, ok := inc.(string)
+			if !ok {
+				return nil, errors.New(errInvalidInclude)
+			}
+			includes = append(includes, s)
+		}
+		return includes, nil
+	}
+	return nil, errors.New(errInvalidInclude)
+}
+
+func isRESFile(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".res")
+}
+
+func importRES(rs *winres.ResourceSet, name string) error {
+	b, err := ioutil.ReadFile(name)
 	if err != nil {
 		return err
 	}
+	err = readRES(rs, b)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
 
-	for tid, t := range res {
-		for _, r := range sortedRes(t) {
+func importDef(rs *winres.ResourceSet, dir string, res jsonDef) error {
+	var (
+		errs  errorList
+		fonts bool
+	)
+
+	errs.add(expandTrees(dir, res))
+	errs.add(checkFontIDs(dir, res))
+
+	for _, tid := range sortedTypes(res) {
+		for _, r := range sortedRes(res[tid]) {
+			if isStringTable(tid, r.id) {
+				errs.add(atPath(importStringTable(rs, r.id, r.langs), tid, r.id))
+				continue
+			}
 			for _, l := range sortedLang(r.langs) {
 				typeID, resID, langID, err := idsFromStrings(tid, r.id, l.id)
+				if err == nil {
+					err = importResource(rs, dir, typeID, resID, langID, l.data)
+				}
 				if err != nil {
-					return err
+					errs.add(atPath(err, tid, r.id, l.id))
+					continue
 				}
-				switch typeID {
-				case winres.RT_ICON:
-					return errors.New("cannot import RT_ICON resources directly, use RT_GROUP_ICON instead")
-				case winres.RT_CURSOR:
-					return errors.New("cannot import RT_CURSOR resources directly, use RT_GROUP_CURSOR instead")
-				case winres.RT_GROUP_CURSOR:
-					cursor, err := loadCursor(dir, l.data)
-					if err != nil {
-						return err
-					}
-					err = rs.SetCursorTranslation(resID, langID, cursor)
-					if err != nil {
-						return err
-					}
-				case winres.RT_GROUP_ICON:
-					icon, err := loadIcon(dir, l.data)
-					if err != nil {
-						return err
-					}
-					err = rs.SetIconTranslation(resID, langID, icon)
-					if err != nil {
-						return err
-					}
-				case winres.RT_VERSION:
-					vi := version.Info{}
-					j, _ := json.Marshal(l.data)
-					err = json.Unmarshal(j, &vi)
-					if err != nil {
-						return err
-					}
-					rs.SetVersionInfo(vi)
-				case winres.RT_BITMAP:
-					filename, ok := l.data.(string)
-					if !ok {
-						return errors.New(errInvalidSet)
-					}
-					dib, err := loadBMP(filepath.Join(dir, filename))
-					if err != nil {
-						return err
-					}
-					err = rs.Set(winres.RT_BITMAP, resID, langID, dib)
-					if err != nil {
-						return err
-					}
-				case winres.RT_MANIFEST:
-					switch val := l.data.(type) {
-					case string:
-						data, err := ioutil.ReadFile(filepath.Join(dir, val))
-						if err != nil {
-							return err
-						}
-						err = rs.Set(typeID, resID, langID, data)
-						if err != nil {
-							return err
-						}
-					default:
-						j, _ := json.Marshal(val)
-						m := winres.AppManifest{}
-						err = json.Unmarshal(j, &m)
-						if err != nil {
-							return err
-						}
-						rs.SetManifest(m)
-					}
-				default:
-					filename, ok := l.data.(string)
-					if !ok {
-						return errors.New(errInvalidSet)
-					}
-					data, err := ioutil.ReadFile(filepath.Join(dir, filename))
-					if err != nil {
-						return err
-					}
-					err = rs.Set(typeID, resID, langID, data)
-					if err != nil {
-						return err
-					}
+				if typeID == winres.RT_FONT {
+					fonts = true
 				}
 			}
 		}
 	}
 
+	// The font directory is generated, unless it is explicitly defined
+	if _, ok := res["RT_FONTDIR"]; fonts && !ok {
+		setFontDir(rs)
+	}
+
+	return errs.err()
+}
+
+// importResource imports one resource, in one language.
+func importResource(rs *winres.ResourceSet, dir string, typeID, resID winres.Identifier, langID uint16, x interface{}) error {
+	var err error
+
+	switch typeID {
+	case winres.RT_ICON:
+		return errors.New("cannot import RT_ICON resources directly, use RT_GROUP_ICON instead")
+	case winres.RT_CURSOR:
+		return errors.New("cannot import RT_CURSOR resources directly, use RT_GROUP_CURSOR instead")
+	case winres.RT_GROUP_CURSOR:
+		cursor, err := loadCursor(dir, x)
+		if err != nil {
+			return err
+		}
+		err = rs.SetCursorTranslation(resID, langID, cursor)
+		if err != nil {
+			return err
+		}
+	case winres.RT_GROUP_ICON:
+		icon, err := loadIcon(dir, x)
+		if err != nil {
+			return err
+		}
+		err = rs.SetIconTranslation(resID, langID, icon)
+		if err != nil {
+			return err
+		}
+	case winres.RT_VERSION:
+		vi := version.Info{}
+		j, _ := json.Marshal(x)
+		err = json.Unmarshal(j, &vi)
+		if err != nil {
+			return err
+		}
+		rs.SetVersionInfo(vi)
+	case winres.RT_MESSAGETABLE:
+		err = importMessageTable(rs, dir, resID, langID, x)
+		if err != nil {
+			return err
+		}
+	case winres.RT_ACCELERATOR:
+		data, err := loadAccelerators(dir, x)
+		if err != nil {
+			return err
+		}
+		err = rs.Set(typeID, resID, langID, data)
+		if err != nil {
+			return err
+		}
+	case winres.RT_MENU:
+		data, err := loadMenu(dir, x)
+		if err != nil {
+			return err
+		}
+		err = rs.Set(typeID, resID, langID, data)
+		if err != nil {
+			return err
+		}
+	case winres.RT_DIALOG:
+		data, err := loadDialog(dir, x)
+		if err != nil {
+			return err
+		}
+		err = rs.Set(typeID, resID, langID, data)
+		if err != nil {
+			return err
+		}
+	case winres.RT_ANICURSOR, winres.RT_ANIICON:
+		data, err := loadAnimation(dir, x, typeID == winres.RT_ANICURSOR)
+		if err != nil {
+			return err
+		}
+		err = rs.Set(typeID, resID, langID, data)
+		if err != nil {
+			return err
+		}
+	case winres.RT_FONT:
+		filename, ok := x.(string)
+		if !ok {
+			return errors.New(errInvalidSet)
+		}
+		err = importFont(rs, filepath.Join(dir, filename), resID, langID)
+		if err != nil {
+			return err
+		}
+	case winres.RT_BITMAP:
+		filename, ok := x.(string)
+		if !ok {
+			return errors.New(errInvalidSet)
+		}
+		dib, err := loadBMP(filepath.Join(dir, filename))
+		if err != nil {
+			return err
+		}
+		err = rs.Set(winres.RT_BITMAP, resID, langID, dib)
+		if err != nil {
+			return err
+		}
+	case winres.RT_MANIFEST:
+		switch val := x.(type) {
+		case string:
+			data, err := ioutil.ReadFile(filepath.Join(dir, val))
+			if err != nil {
+				return err
+			}
+			err = rs.Set(typeID, resID, langID, data)
+			if err != nil {
+				return err
+			}
+		default:
+			j, _ := json.Marshal(val)
+			m := winres.AppManifest{}
+			err = json.Unmarshal(j, &m)
+			if err != nil {
+				return err
+			}
+			rs.SetManifest(m)
+		}
+	default:
+		var data []byte
+		switch val := x.(type) {
+		case string:
+			data, err = ioutil.ReadFile(filepath.Join(dir, val))
+			if err != nil {
+				return err
+			}
+		case []byte:
+			// Raw data from a resource script
+			data = val
+		case map[string]interface{}:
+			data, err = loadInline(val)
+			if err != nil {
+				return err
+			}
+		default:
+			return errors.New(errInvalidSet)
+		}
+		err = rs.Set(typeID, resID, langID, data)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -527,22 +961,52 @@ func loadCursor(dir string, c interface{}) (*winres.Cursor, error) {
 		return loadCUR(filepath.Join(dir, c))
 
 	case []interface{}:
-		var images []winres.CursorImage
+		var (
+			images   []winres.CursorImage
+			hotSpots []*hotSpotDef
+			first    *hotSpotDef
+		)
 		for i := range c {
 			o, ok := c[i].(map[string]interface{})
 			if !ok {
-				return nil, errors.New(errInvalidCursor)
+				return nil, atPath(errors.New(errInvalidCursor), strconv.Itoa(i))
 			}
-			curImg, err := loadCursorImage(dir, o)
+			curImg, hs, err := loadCursorImage(dir, o)
 			if err != nil {
-				return nil, err
+				return nil, atPath(err, strconv.Itoa(i))
+			}
+			if first == nil {
+				first = hs
 			}
 			images = append(images, curImg)
+			hotSpots = append(hotSpots, hs)
+		}
+		if first == nil {
+			return nil, errors.New(errNoHotSpot)
+		}
+		// Images without a hot spot have the same as the first image that has one
+		for i := range images {
+			hs := hotSpots[i]
+			if hs == nil {
+				hs = first
+			}
+			var err error
+			images[i].HotSpot, err = hs.at(images[i].Image.Bounds().Size())
+			if err != nil {
+				return nil, atPath(err, strconv.Itoa(i))
+			}
 		}
 		return winres.NewCursorFromImages(images)
 
 	case map[string]interface{}:
-		curImg, err := loadCursorImage(dir, c)
+		curImg, hs, err := loadCursorImage(dir, c)
+		if err != nil {
+			return nil, err
+		}
+		if hs == nil {
+			return nil, errors.New(errNoHotSpot)
+		}
+		curImg.HotSpot, err = hs.at(curImg.Image.Bounds().Size())
 		if err != nil {
 			return nil, err
 		}
@@ -552,46 +1016,56 @@ func loadCursor(dir string, c interface{}) (*winres.Cursor, error) {
 	return nil, errors.New(errInvalidCursor)
 }
 
-func loadCursorImage(dir string, c map[string]interface{}) (winres.CursorImage, error) {
-	x, xOK := c["x"].(float64)
-	y, yOK := c["y"].(float64)
-	f, fOK := c["image"].(string)
-	if !fOK || !xOK || !yOK {
-		return winres.CursorImage{}, errors.New(errInvalidCursor)
+// loadCursorImage loads the image of a cursor, and reads its hot spot, which may be nil.
+func loadCursorImage(dir string, c map[string]interface{}) (winres.CursorImage, *hotSpotDef, error) {
+	f, ok := c["image"].(string)
+	if !ok {
+		return winres.CursorImage{}, nil, errors.New(errInvalidCursor)
+	}
+	hs, err := readHotSpot(c)
+	if err != nil {
+		return winres.CursorImage{}, nil, err
 	}
 
-	img, err := loadImage(filepath.Join(dir, f))
+	var img image.Image
+	if size, ok := c["size"].(float64); ok {
+		if !isSVG(f) {
+			return winres.CursorImage{}, nil, errors.New(errImageSize)
+		}
+		if size < 1 || size > 256 {
+			return winres.CursorImage{}, nil, fmt.Errorf("%s: %v", errCursorSize, size)
+		}
+		img, err = loadSVGImage(filepath.Join(dir, f), int(size))
+	} else {
+		img, err = loadImage(filepath.Join(dir, f))
+	}
 	if err != nil {
-		return winres.CursorImage{}, err
+		return winres.CursorImage{}, nil, err
+	}
+
+	if hs != nil {
+		hs.size = img.Bounds().Size()
 	}
 
-	return winres.CursorImage{
-		Image:   img,
-		HotSpot: winres.HotSpot{X: uint16(x), Y: uint16(y)},
-	}, nil
+	return winres.CursorImage{Image: img}, hs, nil
 }
 
 func loadIcon(dir string, x interface{}) (*winres.Icon, error) {
 	switch x := x.(type) {
 	case string:
-		if strings.ToLower(filepath.Ext(x)) == ".ico" {
-			return loadICO(filepath.Join(dir, x))
-		}
-		img, err := loadImage(filepath.Join(dir, x))
-		if err != nil {
-			return nil, err
-		}
-		return winres.NewIconFromResizedImage(img, nil)
+		return makeIcon(filepath.Join(dir, x), iconOptions{})
+	case map[string]interface{}:
+		return loadIconObject(dir, x)
 	case []interface{}:
 		var images []image.Image
 		for i := range x {
 			f, ok := x[i].(string)
 			if !ok {
-				return nil, errors.New(errInvalidIcon)
+				return nil, atPath(errors.New(errInvalidIcon), strconv.Itoa(i))
 			}
 			img, err := loadImage(filepath.Join(dir, f))
 			if err != nil {
-				return nil, err
+				return nil, atPath(err, strconv.Itoa(i))
 			}
 			images = append(images, img)
 		}
@@ -600,15 +1074,70 @@ func loadIcon(dir string, x interface{}) (*winres.Icon, error) {
 	return nil, errors.New(errInvalidIcon)
 }
 
+// loadIconObject makes an icon from a main image, with a list of sizes, a resampling filter,
+// and images that replace some of the sizes.
+func loadIconObject(dir string, x map[string]interface{}) (*winres.Icon, error) {
+	name, _, opt, err := iconObject(dir, x)
+	if err != nil {
+		return nil, err
+	}
+	return makeIcon(name, opt)
+}
+
+// iconObject reads the object form of an icon.
+// It returns the path of the main image, the key that gave it, and the options to make the icon.
+func iconObject(dir string, x map[string]interface{}) (string, string, iconOptions, error) {
+	opt := iconOptions{}
+	// "source" is an alias of "image", only one of them can be given
+	key := "image"
+	v, ok := x[key]
+	if s, both := x["source"]; both && ok {
+		return "", "", opt, errors.New(errInvalidIcon)
+	} else if both {
+		key, v = "source", s
+	}
+	name, ok := v.(string)
+	if !ok {
+		return "", "", opt, errors.New(errInvalidIcon)
+	}
+	opt.filter, _ = x["filter"].(string)
+
+	if s, ok := x["sizes"].([]interface{}); ok {
+		for i := range s {
+			n, ok := s[i].(float64)
+			if !ok {
+				return "", "", opt, atPath(errors.New(errInvalidIcon), "sizes", strconv.Itoa(i))
+			}
+			opt.sizes = append(opt.sizes, int(n))
+		}
+	}
+
+	if o, ok := x["overrides"].(map[string]interface{}); ok {
+		opt.overrides = make(map[int]string, len(o))
+		for _, l := range sortedLang(o) {
+			size, err := strconv.Atoi(l.id)
+			f, ok := l.data.(string)
+			if err != nil || !ok {
+				return "", "", opt, atPath(errors.New(errInvalidIcon), "overrides", l.id)
+			}
+			opt.overrides[size] = filepath.Join(dir, f)
+		}
+	}
+
+	return filepath.Join(dir, name), key, opt, nil
+}
+
 func loadImage(name string) (image.Image, error) {
+	if isSVG(name) {
+		return loadSVGImage(name, 0)
+	}
 	f, err := os.Open(name)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	img, _, err := image.Decode(f)
-	return img, err
+	return decodeImage(f)
 }
 
 func loadCUR(name string) (*winres.Cursor, error) {
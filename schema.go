@@ -0,0 +1,552 @@
+package main
+
+const schemaFile = "winres.schema.json"
+
+// schemaJSON is a JSON Schema of winres.json, so that editors can validate it and complete keys.
+//
+// language=json
+const schemaJSON = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "go-winres resource definition",
+  "description": "Resource types, then resource names, then language IDs",
+  "type": "object",
+  "properties": {
+    "$schema": {
+      "type": "string"
+    },
+    "$include": {
+      "description": "A .res file, or a list of .res files, to import before this file",
+      "oneOf": [
+        {"type": "string"},
+        {"type": "array", "items": {"type": "string"}}
+      ]
+    },
+    "RT_ICON": {
+      "description": "Use RT_GROUP_ICON instead",
+      "not": {}
+    },
+    "RT_CURSOR": {
+      "description": "Use RT_GROUP_CURSOR instead",
+      "not": {}
+    },
+    "RT_GROUP_ICON": {
+      "description": "Icons, the first one is the icon of the executable",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/icon"}
+      }
+    },
+    "RT_GROUP_CURSOR": {
+      "description": "Cursors",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/cursor"}
+      }
+    },
+    "RT_ANICURSOR": {
+      "description": "Animated cursors",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/animation"}
+      }
+    },
+    "RT_ANIICON": {
+      "description": "Animated icons",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/animation"}
+      }
+    },
+    "RT_BITMAP": {
+      "description": "Bitmaps, from .bmp files",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/fileName"}
+      }
+    },
+    "RT_FONT": {
+      "description": "Fonts (.ttf, .otf, .fnt or .fon), with a numeric ID such as \"#1\"",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceID"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/fileName"}
+      }
+    },
+    "RT_STRING": {
+      "description": "String tables, by language, then by string ID",
+      "type": "object",
+      "patternProperties": {
+        "^[0-9A-Fa-f]{1,4}$": {
+          "type": "object",
+          "propertyNames": {"pattern": "^#?[0-9]+$"},
+          "additionalProperties": {"type": "string"}
+        },
+        "^#": {
+          "type": "object",
+          "propertyNames": {"$ref": "#/definitions/languageID"},
+          "additionalProperties": {"$ref": "#/definitions/data"}
+        }
+      },
+      "additionalProperties": false
+    },
+    "RT_MESSAGETABLE": {
+      "description": "Message tables",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/messageTable"}
+      }
+    },
+    "RT_ACCELERATOR": {
+      "description": "Accelerator tables",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/accelerators"}
+      }
+    },
+    "RT_MENU": {
+      "description": "Menus",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/menu"}
+      }
+    },
+    "RT_DIALOG": {
+      "description": "Dialog templates",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/dialog"}
+      }
+    },
+    "RT_HTML": {"$ref": "#/definitions/treeResources"},
+    "RT_RCDATA": {"$ref": "#/definitions/treeResources"},
+    "RT_MANIFEST": {
+      "description": "Application manifest, which should be resource \"#1\" with language \"0409\"",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/manifest"}
+      }
+    },
+    "RT_VERSION": {
+      "description": "Version information, which should be resource \"#1\"",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/versionInfo"}
+      }
+    }
+  },
+  "patternProperties": {
+    "^\\$": {}
+  },
+  "propertyNames": {"$ref": "#/definitions/resourceName"},
+  "additionalProperties": {"$ref": "#/definitions/resources"},
+  "definitions": {
+    "resourceName": {
+      "description": "A name, or a numeric ID such as \"#1\"",
+      "type": "string",
+      "minLength": 1
+    },
+    "resourceID": {
+      "description": "A numeric ID such as \"#1\"",
+      "type": "string",
+      "pattern": "^#[0-9]+$"
+    },
+    "languageID": {
+      "description": "Hexadecimal language ID, such as \"0409\" for en-US, or \"0000\" for neutral",
+      "type": "string",
+      "pattern": "^[0-9A-Fa-f]{1,4}$"
+    },
+    "fileName": {
+      "description": "Path of a file, relative to this file",
+      "type": "string"
+    },
+    "inline": {
+      "description": "Data written in this file",
+      "type": "object",
+      "properties": {
+        "text": {"type": "string"},
+        "encoding": {"enum": ["utf-8", "utf-16le", "utf-16", "utf-16be", "latin1"]},
+        "base64": {"type": "string"},
+        "hex": {"type": "string"}
+      },
+      "oneOf": [
+        {"required": ["text"]},
+        {"required": ["base64"]},
+        {"required": ["hex"]}
+      ],
+      "additionalProperties": false
+    },
+    "data": {
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {"$ref": "#/definitions/inline"}
+      ]
+    },
+    "resources": {
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/data"}
+      }
+    },
+    "treeResources": {
+      "description": "A name ending with \"*\" imports a directory tree",
+      "type": "object",
+      "propertyNames": {"$ref": "#/definitions/resourceName"},
+      "patternProperties": {
+        "\\*$": {
+          "type": "object",
+          "propertyNames": {"$ref": "#/definitions/languageID"},
+          "additionalProperties": {"$ref": "#/definitions/tree"}
+        }
+      },
+      "additionalProperties": {
+        "type": "object",
+        "propertyNames": {"$ref": "#/definitions/languageID"},
+        "additionalProperties": {"$ref": "#/definitions/data"}
+      }
+    },
+    "tree": {
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {
+          "type": "object",
+          "properties": {
+            "dir": {"type": "string"},
+            "include": {"type": "array", "items": {"type": "string"}},
+            "exclude": {"type": "array", "items": {"type": "string"}}
+          },
+          "required": ["dir"],
+          "additionalProperties": false
+        }
+      ]
+    },
+    "icon": {
+      "description": "An icon file, a list of images of different sizes, or an image to resize",
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {"type": "array", "items": {"$ref": "#/definitions/fileName"}},
+        {
+          "type": "object",
+          "properties": {
+            "image": {"description": "Main image, resized to each size", "$ref": "#/definitions/fileName"},
+            "source": {"description": "Same as image", "$ref": "#/definitions/fileName"},
+            "sizes": {"type": "array", "maxItems": 30, "items": {"type": "integer", "minimum": 1, "maximum": 256}},
+            "filter": {"description": "Resampling filter", "enum": ["nearest", "bilinear", "catmull-rom", "mitchell", "lanczos2", "lanczos"]},
+            "overrides": {
+              "description": "Images to use instead of resizing the main image, by size",
+              "type": "object",
+              "propertyNames": {"pattern": "^[0-9]{1,3}$"},
+              "additionalProperties": {"$ref": "#/definitions/fileName"}
+            }
+          },
+          "oneOf": [{"required": ["image"]}, {"required": ["source"]}],
+          "additionalProperties": false
+        }
+      ]
+    },
+    "hotSpot": {
+      "description": "Hot spot, as a named position or two coordinates in pixels or percents, such as \"50%,50%\"",
+      "anyOf": [
+        {"enum": ["top-left", "top", "top-right", "left", "center", "right", "bottom-left", "bottom", "bottom-right"]},
+        {"type": "string", "pattern": "^\\s*-?[0-9.]+%?\\s*,\\s*-?[0-9.]+%?\\s*$"}
+      ]
+    },
+    "cursorImage": {
+      "type": "object",
+      "properties": {
+        "image": {"$ref": "#/definitions/fileName"},
+        "x": {"description": "Hot spot", "type": "integer", "minimum": 0},
+        "y": {"description": "Hot spot", "type": "integer", "minimum": 0},
+        "hotspot": {"$ref": "#/definitions/hotSpot"},
+        "size": {"description": "Size in pixels an svg image is rendered at", "type": "integer", "minimum": 1, "maximum": 256}
+      },
+      "required": ["image"],
+      "additionalProperties": false
+    },
+    "cursor": {
+      "description": "A cursor file, an image with its hot spot, or a list of images of different sizes",
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {"$ref": "#/definitions/cursorImage"},
+        {"type": "array", "items": {"$ref": "#/definitions/cursorImage"}}
+      ]
+    },
+    "animation": {
+      "description": "An .ani file, or a list of frames",
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {
+          "type": "object",
+          "properties": {
+            "rate": {"description": "Default display time of a frame, in 1/60 s", "type": "integer", "minimum": 1},
+            "frames": {
+              "type": "array",
+              "minItems": 1,
+              "items": {
+                "oneOf": [
+                  {"$ref": "#/definitions/fileName"},
+                  {
+                    "type": "object",
+                    "properties": {
+                      "image": {"$ref": "#/definitions/fileName"},
+                      "x": {"description": "Hot spot", "type": "integer", "minimum": 0},
+                      "y": {"description": "Hot spot", "type": "integer", "minimum": 0},
+                      "hotspot": {"$ref": "#/definitions/hotSpot"},
+                      "rate": {"type": "integer", "minimum": 1}
+                    },
+                    "required": ["image"],
+                    "additionalProperties": false
+                  }
+                ]
+              }
+            },
+            "sequence": {"description": "Order of the frames", "type": "array", "items": {"type": "integer", "minimum": 0}}
+          },
+          "required": ["frames"],
+          "additionalProperties": false
+        }
+      ]
+    },
+    "messageTable": {
+      "description": "A .mc file, a compiled message table, or a list of messages",
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "properties": {
+              "id": {"type": "integer", "minimum": 0, "maximum": 65535},
+              "severity": {"enum": ["success", "informational", "warning", "error"]},
+              "facility": {"type": "integer", "minimum": 0, "maximum": 4095},
+              "customer": {"type": "boolean"},
+              "text": {"type": "string"}
+            },
+            "required": ["id", "text"],
+            "additionalProperties": false
+          }
+        }
+      ]
+    },
+    "accelerators": {
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "properties": {
+              "key": {"description": "A virtual key such as \"F1\", or a character", "type": "string"},
+              "type": {"enum": ["virtkey", "ascii", "VIRTKEY", "ASCII"]},
+              "modifiers": {"description": "Comma separated list of \"Ctrl\", \"Shift\" and \"Alt\"", "type": "string"},
+              "noinvert": {"type": "boolean"},
+              "id": {"type": "integer", "minimum": 0, "maximum": 65535}
+            },
+            "required": ["key", "id"],
+            "additionalProperties": false
+          }
+        }
+      ]
+    },
+    "menuItem": {
+      "type": "object",
+      "properties": {
+        "text": {"type": "string"},
+        "id": {"type": "integer", "minimum": 0},
+        "separator": {"type": "boolean"},
+        "flags": {"type": "string"},
+        "help_id": {"type": "integer", "minimum": 0},
+        "items": {"type": "array", "items": {"$ref": "#/definitions/menuItem"}}
+      },
+      "additionalProperties": false
+    },
+    "menu": {
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {"type": "array", "items": {"$ref": "#/definitions/menuItem"}}
+      ]
+    },
+    "dialog": {
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {
+          "type": "object",
+          "properties": {
+            "x": {"type": "integer"},
+            "y": {"type": "integer"},
+            "width": {"type": "integer"},
+            "height": {"type": "integer"},
+            "title": {"type": "string"},
+            "style": {"type": "string"},
+            "ex_style": {"type": "string"},
+            "help_id": {"type": "integer", "minimum": 0},
+            "menu": {"type": "string"},
+            "class": {"type": "string"},
+            "font": {
+              "type": "object",
+              "properties": {
+                "name": {"type": "string"},
+                "size": {"type": "integer", "minimum": 0},
+                "weight": {"type": "integer", "minimum": 0},
+                "italic": {"type": "boolean"},
+                "charset": {"type": "integer", "minimum": 0, "maximum": 255}
+              },
+              "required": ["name", "size"],
+              "additionalProperties": false
+            },
+            "controls": {
+              "type": "array",
+              "items": {
+                "type": "object",
+                "properties": {
+                  "class": {"type": "string"},
+                  "text": {"type": "string"},
+                  "id": {"type": "integer", "minimum": 0},
+                  "x": {"type": "integer"},
+                  "y": {"type": "integer"},
+                  "width": {"type": "integer"},
+                  "height": {"type": "integer"},
+                  "style": {"type": "string"},
+                  "ex_style": {"type": "string"},
+                  "help_id": {"type": "integer", "minimum": 0},
+                  "data": {"description": "Creation data, in base64", "type": "string"}
+                },
+                "required": ["class"],
+                "additionalProperties": false
+              }
+            }
+          },
+          "additionalProperties": false
+        }
+      ]
+    },
+    "manifest": {
+      "description": "An xml file, or the settings of a generated manifest",
+      "oneOf": [
+        {"$ref": "#/definitions/fileName"},
+        {
+          "type": "object",
+          "properties": {
+            "identity": {
+              "description": "Identity of a side-by-side assembly, better omitted in a plain application",
+              "type": "object",
+              "properties": {
+                "name": {"type": "string"},
+                "version": {"type": "string", "pattern": "^\\s*$|^[0-9]+(\\.[0-9]+){0,3}$"}
+              },
+              "additionalProperties": false
+            },
+            "description": {"type": "string"},
+            "minimum-os": {
+              "enum": ["vista", "win7", "win8", "win8.1", "win10"]
+            },
+            "execution-level": {
+              "enum": ["", "as invoker", "asinvoker", "highest", "highest available", "highestavailable", "administrator", "require administrator", "requireadministrator"]
+            },
+            "ui-access": {"description": "Allow UI automation to drive windows of other processes", "type": "boolean"},
+            "auto-elevate": {"type": "boolean"},
+            "dpi-awareness": {
+              "enum": ["unaware", "false", "system", "true", "", "per monitor", "permonitor", "true/pm", "per monitor v2", "permonitorv2"]
+            },
+            "disable-theming": {"type": "boolean"},
+            "disable-window-filtering": {"type": "boolean"},
+            "high-resolution-scrolling-aware": {"type": "boolean"},
+            "ultra-high-resolution-scrolling-aware": {"type": "boolean"},
+            "long-path-aware": {"type": "boolean"},
+            "printer-driver-isolation": {"type": "boolean"},
+            "gdi-scaling": {"type": "boolean"},
+            "segment-heap": {"type": "boolean"},
+            "use-common-controls-v6": {"type": "boolean"}
+          },
+          "additionalProperties": false
+        }
+      ]
+    },
+    "versionNumber": {
+      "description": "Up to four numbers, such as \"1.2.3.4\"",
+      "type": "string",
+      "pattern": "^$|^[0-9]+(\\.[0-9]+){0,3}$"
+    },
+    "versionInfo": {
+      "type": "object",
+      "properties": {
+        "fixed": {
+          "type": "object",
+          "properties": {
+            "file_version": {"$ref": "#/definitions/versionNumber"},
+            "product_version": {"$ref": "#/definitions/versionNumber"},
+            "flags": {
+              "description": "Comma separated list of \"Debug\", \"Prerelease\", \"Patched\", \"PrivateBuild\" and \"SpecialBuild\"",
+              "type": "string"
+            },
+            "type": {"enum": ["", "App", "app", "DLL", "dll", "Unknown"]},
+            "timestamp": {"type": "string", "format": "date-time"}
+          },
+          "additionalProperties": false
+        },
+        "info": {
+          "description": "File properties, by language",
+          "type": "object",
+          "propertyNames": {"$ref": "#/definitions/languageID"},
+          "additionalProperties": {
+            "type": "object",
+            "properties": {
+              "Comments": {"type": "string"},
+              "CompanyName": {"type": "string"},
+              "FileDescription": {"type": "string"},
+              "FileVersion": {"type": "string"},
+              "InternalName": {"type": "string"},
+              "LegalCopyright": {"type": "string"},
+              "LegalTrademarks": {"type": "string"},
+              "OriginalFilename": {"type": "string"},
+              "PrivateBuild": {"type": "string"},
+              "ProductName": {"type": "string"},
+              "ProductVersion": {"type": "string"},
+              "SpecialBuild": {"type": "string"}
+            },
+            "additionalProperties": {"type": "string"}
+          }
+        }
+      },
+      "additionalProperties": false
+    }
+  }
+}
+`
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_schemaJSON(t *testing.T) {
+	var schema struct {
+		Properties  map[string]interface{} `json:"properties"`
+		Definitions map[string]struct {
+			OneOf []struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"oneOf"`
+			Properties map[string]interface{} `json:"properties"`
+			AnyOf      []interface{}          `json:"anyOf"`
+		} `json:"definitions"`
+	}
+	err := json.Unmarshal([]byte(schemaJSON), &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every key of the manifest must be known, so that typos are detected
+	b, _ := json.Marshal(winres.AppManifest{})
+	var manifest map[string]interface{}
+	json.Unmarshal(b, &manifest)
+	props := schema.Definitions["manifest"].OneOf[1].Properties
+	for k := range manifest {
+		if _, ok := props[k]; !ok {
+			t.Errorf("manifest key %q is missing", k)
+		}
+	}
+	if len(props) != len(manifest) {
+		t.Errorf("manifest has %d keys, want %d", len(props), len(manifest))
+	}
+
+	for _, k := range []string{"fixed", "info"} {
+		if _, ok := schema.Definitions["versionInfo"].Properties[k]; !ok {
+			t.Errorf("version info key %q is missing", k)
+		}
+	}
+
+	filter, _ := schema.Definitions["icon"].OneOf[2].Properties["filter"].(map[string]interface{})
+	enum, _ := filter["enum"].([]interface{})
+	if len(enum) != len(resizeFilters) {
+		t.Errorf("icon filter has %d values, want %d", len(enum), len(resizeFilters))
+	}
+	for _, f := range enum {
+		if _, ok := resizeFilters[f.(string)]; !ok {
+			t.Errorf("unknown icon filter %q", f)
+		}
+	}
+
+	anyOf := schema.Definitions["hotSpot"].AnyOf
+	if len(anyOf) == 0 {
+		t.Fatal("hotspot is missing")
+	}
+	anchors, _ := anyOf[0].(map[string]interface{})["enum"].([]interface{})
+	if len(anchors) != len(hotSpotAnchors) {
+		t.Errorf("hotspot has %d anchors, want %d", len(anchors), len(hotSpotAnchors))
+	}
+	for _, a := range anchors {
+		if _, ok := hotSpotAnchors[a.(string)]; !ok {
+			t.Errorf("unknown hotspot anchor %q", a)
+		}
+	}
+
+	for _, typeName := range typeIDToString {
+		switch typeName {
+		case "RT_FONTDIR", "RT_PLUGPLAY", "RT_VXD":
+			continue
+		}
+		if _, ok := schema.Properties[typeName]; !ok {
+			t.Errorf("type %s is missing", typeName)
+		}
+	}
+
+	// String IDs accepted by the schema must be those accepted by parseStringID
+	var strs struct {
+		PatternProperties map[string]struct {
+			PropertyNames struct {
+				Pattern string `json:"pattern"`
+			} `json:"propertyNames"`
+		} `json:"patternProperties"`
+	}
+	b, _ = json.Marshal(schema.Properties["RT_STRING"])
+	json.Unmarshal(b, &strs)
+	re, err := regexp.Compile(strs.PatternProperties["^[0-9A-Fa-f]{1,4}$"].PropertyNames.Pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "#123", "65535", "x", "#", "1#"} {
+		_, err := parseStringID(id)
+		if re.MatchString(id) != (err == nil) {
+			t.Errorf("string ID %q: schema and parser disagree", id)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errInvalidStringID    = "invalid string identifier"
+	errInvalidStringTable = "invalid string table definition"
+	errStringTooLong      = "string too long"
+	errInvalidStringBlock = "invalid string block"
+)
+
+// A string table is stored as blocks of 16 strings.
+// Block N holds strings (N-1)*16 to (N-1)*16+15, each string being prefixed by its length in UTF-16 code units.
+//
+// https://devblogs.microsoft.com/oldnewthing/20040130-00/?p=40813
+const stringsPerBlock = 16
+
+// isStringTable tells if a resource name in a RT_STRING definition is actually a language.
+//
+// In winres.json, string tables skip the resource name level:
+// strings are listed by language, then by string ID.
+// Raw string blocks can still be imported as files, if they are named with an ID such as "#63".
+func isStringTable(typeID string, name string) bool {
+	return typeID == typeIDToString[winres.RT_STRING] && !strings.HasPrefix(name, "#")
+}
+
+// importStringTable packs strings into blocks and adds them to the resource set.
+//
+// Invalid strings are all reported, with their string ID as path.
+func importStringTable(rs *winres.ResourceSet, l string, table map[string]interface{}) error {
+	n, err := strconv.ParseUint(l, 16, 16)
+	if err != nil {
+		return errors.New("invalid language identifier")
+	}
+	langID := uint16(n)
+
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs errorList
+	strs := make(map[uint16]string, len(table))
+	for _, k := range keys {
+		v := table[k]
+		id, err := parseStringID(k)
+		if err != nil {
+			errs.add(atPath(err, k))
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			errs.add(atPath(errors.New(errInvalidStringTable), k))
+			continue
+		}
+		strs[id] = s
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// When patching an executable, strings that share a block with new strings must be kept.
+	merged := make(map[uint16]string)
+	for id := range strs {
+		blockID := winres.ID(id/stringsPerBlock + 1)
+		old, err := readStringBlock(blockID, rs.Get(winres.RT_STRING, blockID, langID))
+		if err != nil {
+			continue
+		}
+		for oldID, s := range old {
+			merged[oldID] = s
+		}
+	}
+	for id, s := range strs {
+		merged[id] = s
+	}
+
+	blocks, err := makeStringBlocks(merged)
+	if err != nil {
+		return err
+	}
+	for blockID, data := range blocks {
+		err = rs.Set(winres.RT_STRING, winres.ID(blockID), langID, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseStringID(s string) (uint16, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q", errInvalidStringID, s)
+	}
+	return uint16(n), nil
+}
+
+// makeStringBlocks packs strings into RT_STRING blocks, indexed by block ID.
+//
+// Empty strings are not stored, because LoadString can't tell them from missing strings anyway.
+func makeStringBlocks(strs map[uint16]string) (map[uint16][]byte, error) {
+	tables := make(map[uint16]*[stringsPerBlock][]uint16)
+	for id, s := range strs {
+		if s == "" {
+			continue
+		}
+		u := utf16.Encode([]rune(s))
+		if len(u) > 0xFFFF {
+			return nil, fmt.Errorf("%s: %d", errStringTooLong, id)
+		}
+		blockID := id/stringsPerBlock + 1
+		if tables[blockID] == nil {
+			tables[blockID] = &[stringsPerBlock][]uint16{}
+		}
+		tables[blockID][id%stringsPerBlock] = u
+	}
+
+	blocks := make(map[uint16][]byte, len(tables))
+	for blockID, t := range tables {
+		var data []byte
+		for _, u := range t {
+			data = appendUint16(data, uint16(len(u)))
+			for _, c := range u {
+				data = appendUint16(data, c)
+			}
+		}
+		blocks[blockID] = data
+	}
+
+	return blocks, nil
+}
+
+// readStringBlock unpacks a RT_STRING block.
+//
+// It returns non-empty strings indexed by string ID.
+func readStringBlock(resID winres.Identifier, data []byte) (map[uint16]string, error) {
+	blockID, ok := resID.(winres.ID)
+	if !ok || blockID == 0 || blockID > 0x1000 {
+		return nil, errors.New(errInvalidStringBlock)
+	}
+
+	strs := make(map[uint16]string)
+	pos := 0
+	for i := 0; i < stringsPerBlock; i++ {
+		if pos+2 > len(data) {
+			return nil, errors.New(errInvalidStringBlock)
+		}
+		length := int(binary.LittleEndian.Uint16(data[pos:]))
+		pos += 2
+		if pos+length*2 > len(data) {
+			return nil, errors.New(errInvalidStringBlock)
+		}
+		if length > 0 {
+			u := make([]uint16, length)
+			for j := range u {
+				u[j] = binary.LittleEndian.Uint16(data[pos+j*2:])
+			}
+			strs[uint16(blockID-1)*stringsPerBlock+uint16(i)] = string(utf16.Decode(u))
+		}
+		pos += length * 2
+	}
+
+	return strs, nil
+}
+
+func appendUint16(b []byte, n uint16) []byte {
+	return append(b, byte(n), byte(n>>8))
+}
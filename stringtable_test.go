@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_makeStringBlocks(t *testing.T) {
+	blocks, err := makeStringBlocks(map[uint16]string{
+		1:     "A",
+		2:     "",
+		17:    "é€",
+		65535: "Last",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(blocks))
+	}
+
+	want := []byte{0, 0, 1, 0, 'A', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if !bytes.Equal(blocks[1], want) {
+		t.Errorf("block 1 = %v, want %v", blocks[1], want)
+	}
+
+	for blockID, data := range blocks {
+		strs, err := readStringBlock(winres.ID(blockID), data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id, s := range strs {
+			if id/stringsPerBlock+1 != blockID {
+				t.Errorf("string %d found in block %d", id, blockID)
+			}
+			switch id {
+			case 1, 17, 65535:
+			default:
+				t.Errorf("unexpected string %d: %q", id, s)
+			}
+		}
+	}
+
+	strs, _ := readStringBlock(winres.ID(2), blocks[2])
+	if strs[17] != "é€" {
+		t.Errorf("string 17 = %q", strs[17])
+	}
+	strs, _ = readStringBlock(winres.ID(4096), blocks[4096])
+	if strs[65535] != "Last" {
+		t.Errorf("string 65535 = %q", strs[65535])
+	}
+}
+
+func Test_readStringBlock(t *testing.T) {
+	tests := []struct {
+		name  string
+		resID winres.Identifier
+		data  []byte
+		want  map[uint16]string
+		err   bool
+	}{
+		{
+			name:  "name",
+			resID: winres.Name("A"),
+			data:  make([]byte, 32),
+			err:   true,
+		},
+		{
+			name:  "zero",
+			resID: winres.ID(0),
+			data:  make([]byte, 32),
+			err:   true,
+		},
+		{
+			name:  "too big",
+			resID: winres.ID(4097),
+			data:  make([]byte, 32),
+			err:   true,
+		},
+		{
+			name:  "short",
+			resID: winres.ID(1),
+			data:  make([]byte, 30),
+			err:   true,
+		},
+		{
+			name:  "truncated string",
+			resID: winres.ID(1),
+			data:  append([]byte{2, 0, 'A', 0}, make([]byte, 30)...)[:32],
+			err:   true,
+		},
+		{
+			name:  "empty",
+			resID: winres.ID(3),
+			data:  make([]byte, 32),
+			want:  map[uint16]string{},
+		},
+		{
+			name:  "ok",
+			resID: winres.ID(3),
+			data:  append([]byte{0, 0, 2, 0, 'O', 0, 'K', 0}, make([]byte, 28)...),
+			want:  map[uint16]string{33: "OK"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readStringBlock(tt.resID, tt.data)
+			if (err != nil) != tt.err {
+				t.Fatalf("readStringBlock() error = %v, want error %v", err, tt.err)
+			}
+			if !tt.err && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readStringBlock() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_importStringTable(t *testing.T) {
+	rs := &winres.ResourceSet{}
+
+	err := importStringTable(rs, "0409", map[string]interface{}{"1": "One", "2": "Two", "#100": "Hundred"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = importStringTable(rs, "0409", map[string]interface{}{"2": "Deux", "3": "Trois"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strs, err := readStringBlock(winres.ID(1), rs.Get(winres.RT_STRING, winres.ID(1), 0x409))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint16]string{1: "One", 2: "Deux", 3: "Trois"}
+	if !reflect.DeepEqual(strs, want) {
+		t.Errorf("block 1 = %v, want %v", strs, want)
+	}
+	strs, _ = readStringBlock(winres.ID(7), rs.Get(winres.RT_STRING, winres.ID(7), 0x409))
+	if strs[100] != "Hundred" {
+		t.Errorf("string 100 = %q", strs[100])
+	}
+
+	err = importStringTable(rs, "0409", map[string]interface{}{"A": "B", "1": 1.0, "2": "C"})
+	wantErr := "[1] " + errInvalidStringTable + "\n[A] " + errInvalidStringID + `: "A"`
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("importStringTable() error = %v, want %q", err, wantErr)
+	}
+	if importStringTable(rs, "X", map[string]interface{}{"1": "A"}) == nil {
+		t.Error("expected an error for an invalid language")
+	}
+}
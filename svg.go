@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+const (
+	errSVGSize    = "svg image has no size (it needs a viewBox, or a width and a height)"
+	errImageSize  = "size can only be set for svg images"
+	errCursorSize = "invalid cursor size (expected 1 to 256)"
+)
+
+func isSVG(name string) bool {
+	return strings.ToLower(filepath.Ext(name)) == ".svg"
+}
+
+func loadSVG(name string) (*oksvg.SvgIcon, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	svg, err := oksvg.ReadIconStream(f)
+	if err != nil {
+		return nil, err
+	}
+	if svg.ViewBox.W <= 0 || svg.ViewBox.H <= 0 {
+		return nil, errors.New(errSVGSize)
+	}
+	return svg, nil
+}
+
+// svgSize returns the size of an svg image in pixels, as given by its viewBox.
+func svgSize(svg *oksvg.SvgIcon) (int, int) {
+	return int(math.Ceil(svg.ViewBox.W)), int(math.Ceil(svg.ViewBox.H))
+}
+
+// renderSVG draws an svg image in a width x height image.
+// The aspect ratio is kept, and the drawing is centered.
+func renderSVG(svg *oksvg.SvgIcon, width, height int) image.Image {
+	scale := math.Min(float64(width)/svg.ViewBox.W, float64(height)/svg.ViewBox.H)
+	w, h := svg.ViewBox.W*scale, svg.ViewBox.H*scale
+	svg.SetTarget((float64(width)-w)/2, (float64(height)-h)/2, w, h)
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
+	svg.Draw(rasterx.NewDasher(width, height, scanner), 1)
+	return img
+}
+
+// loadSVGImage renders an svg file in a size x size image, or at its own size when size is 0.
+func loadSVGImage(name string, size int) (image.Image, error) {
+	svg, err := loadSVG(name)
+	if err != nil {
+		return nil, err
+	}
+	if size > 0 {
+		return renderSVG(svg, size, size), nil
+	}
+	w, h := svgSize(svg)
+	return renderSVG(svg, w, h), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"testing"
+)
+
+func Test_loadSVGImage(t *testing.T) {
+	img, err := loadSVGImage("_testdata/icon.svg", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 50 {
+		t.Fatalf("expected the viewBox size, got %v", img.Bounds())
+	}
+
+	// The image is fitted and centered, without blurring the edges
+	img, err = loadSVGImage("_testdata/icon.svg", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		x, y int
+		c    color.NRGBA
+	}{
+		{0, 0, color.NRGBA{}},
+		{7, 3, color.NRGBA{}},
+		{0, 4, color.NRGBA{255, 0, 0, 255}},
+		{7, 11, color.NRGBA{255, 0, 0, 255}},
+		{8, 4, color.NRGBA{0, 0, 255, 255}},
+		{15, 11, color.NRGBA{0, 0, 255, 255}},
+		{15, 12, color.NRGBA{}},
+	}
+	for _, tt := range tests {
+		if c := color.NRGBAModel.Convert(img.At(tt.x, tt.y)); c != tt.c {
+			t.Errorf("at (%d, %d), got %v, want %v", tt.x, tt.y, c, tt.c)
+		}
+	}
+}
+
+func Test_loadSVGImage_Err(t *testing.T) {
+	_, err := loadSVGImage("_testdata/test.json", 0)
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func Test_loadIcon_SVG(t *testing.T) {
+	icon, err := loadIcon("_testdata", "icon.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err = icon.SaveICO(buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := binary.LittleEndian.Uint16(buf.Bytes()[4:]); n != 5 {
+		t.Errorf("expected 5 images, got %d", n)
+	}
+}
+
+func Test_loadCursorImage_Size(t *testing.T) {
+	c, _, err := loadCursorImage("_testdata", map[string]interface{}{"image": "icon.svg", "size": 32.0, "x": 1.0, "y": 2.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Image.Bounds().Dx() != 32 || c.Image.Bounds().Dy() != 32 {
+		t.Errorf("got size %v", c.Image.Bounds())
+	}
+
+	_, _, err = loadCursorImage("_testdata", map[string]interface{}{"image": "cur-32x64.png", "size": 32.0, "x": 1.0, "y": 2.0})
+	if err == nil || err.Error() != errImageSize {
+		t.Errorf("expected %q, got %v", errImageSize, err)
+	}
+
+	for _, size := range []float64{-1, 0, 257, 1e9} {
+		_, _, err = loadCursorImage("_testdata", map[string]interface{}{"image": "icon.svg", "size": size, "x": 0.0, "y": 0.0})
+		if want := fmt.Sprintf("%s: %v", errCursorSize, size); err == nil || err.Error() != want {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tc-hib/winres"
+)
+
+const (
+	errInvalidTree = "invalid directory definition"
+	errTreeType    = "directories can only be imported as RT_HTML or RT_RCDATA"
+)
+
+// A resource name ending with this suffix imports a directory tree.
+const treeSuffix = "*"
+
+// tree is the JSON form of a directory tree.
+// It can also be given as a simple string, which is the directory.
+type tree struct {
+	Dir     string   `json:"dir"`
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// expandTrees replaces directory trees with one resource per file.
+//
+// Each file is named after its relative path, in upper case, with slashes,
+// because FindResource converts names to upper case.
+// Resources that are explicitly defined are not replaced.
+// Two trees that import a file under the same name and language are an error.
+// Trees that can't be expanded are removed, and all their errors are returned.
+func expandTrees(dir string, res jsonDef) error {
+	var errs errorList
+	for _, tid := range sortedTypes(res) {
+		t := res[tid]
+		// The map is not modified while ranging over it,
+		// so expansions are collected and applied afterwards.
+		var trees []string
+		files := make(map[string]map[string]interface{})
+		origins := make(map[string]map[string]string)
+		for _, r := range sortedRes(t) {
+			rid := r.id
+			if !strings.HasSuffix(rid, treeSuffix) {
+				continue
+			}
+			trees = append(trees, rid)
+			if tid != "RT_HTML" && tid != "RT_RCDATA" {
+				errs.add(atPath(errors.New(errTreeType), tid, rid))
+				continue
+			}
+			prefix := strings.TrimSuffix(rid, treeSuffix)
+
+			for _, l := range sortedLang(r.langs) {
+				lid := l.id
+				tf, err := treeFiles(dir, l.data)
+				if err != nil {
+					errs.add(atPath(err, tid, rid, lid))
+					continue
+				}
+				rels := make([]string, 0, len(tf))
+				for rel := range tf {
+					rels = append(rels, rel)
+				}
+				sort.Strings(rels)
+				for _, rel := range rels {
+					name := tf[rel]
+					r := prefix + strings.ToUpper(rel)
+					if files[r] == nil {
+						files[r] = make(map[string]interface{})
+						origins[r] = make(map[string]string)
+					}
+					if other, ok := origins[r][lid]; ok {
+						errs.add(atPath(fmt.Errorf("%q is also imported by %q", r, other), tid, rid, lid))
+						continue
+					}
+					files[r][lid] = name
+					origins[r][lid] = rid
+				}
+			}
+		}
+
+		for _, rid := range trees {
+			delete(t, rid)
+		}
+		for r, langs := range files {
+			if t[r] == nil {
+				t[r] = make(map[string]interface{})
+			}
+			for lid, name := range langs {
+				if _, ok := t[r][lid]; !ok {
+					t[r][lid] = name
+				}
+			}
+		}
+	}
+	return errs.err()
+}
+
+// treeFiles returns the files of a directory tree that match the patterns.
+// Keys are slash separated relative paths, values are names relative to dir.
+func treeFiles(dir string, x interface{}) (map[string]string, error) {
+	var tr tree
+	switch x := x.(type) {
+	case string:
+		tr.Dir = x
+	case map[string]interface{}:
+		j, _ := json.Marshal(x)
+		err := json.Unmarshal(j, &tr)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New(errInvalidTree)
+	}
+	if tr.Dir == "" {
+		return nil, errors.New(errInvalidTree)
+	}
+	for _, p := range append(tr.Include, tr.Exclude...) {
+		if _, err := path.Match(strings.TrimSuffix(p, "/"), ""); err != nil {
+			return nil, fmt.Errorf("%v: %q", err, p)
+		}
+	}
+
+	root := filepath.Join(dir, tr.Dir)
+	files := make(map[string]string)
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if len(tr.Include) > 0 && !matchAny(tr.Include, rel) || matchAny(tr.Exclude, rel) {
+			return nil
+		}
+		files[rel] = filepath.Join(tr.Dir, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// matchAny tells if a relative path matches one of the patterns.
+//
+// A pattern without a slash matches the base name, like in .gitignore.
+// A pattern ending with a slash matches a directory and its content.
+// Other patterns match the whole path.
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		var ok bool
+		switch {
+		case strings.HasSuffix(p, "/"):
+			p = strings.TrimSuffix(p, "/")
+			for d := path.Dir(rel); d != "." && !ok; d = path.Dir(d) {
+				if strings.Contains(p, "/") {
+					ok, _ = path.Match(p, d)
+				} else {
+					ok, _ = path.Match(p, path.Base(d))
+				}
+			}
+		case strings.Contains(p, "/"):
+			ok, _ = path.Match(p, rel)
+		default:
+			ok, _ = path.Match(p, path.Base(rel))
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// treePath returns the path of a resource in an extracted directory tree,
+// or an empty string if the resource is not part of a tree.
+//
+// Names that contain a slash always belong to a tree, other names only when all is true.
+func treePath(typeID, resID winres.Identifier, all bool) string {
+	if typeID != winres.RT_HTML && typeID != winres.RT_RCDATA {
+		return ""
+	}
+	name, ok := resID.(winres.Name)
+	if !ok || !all && !strings.Contains(string(name), "/") {
+		return ""
+	}
+	for _, s := range strings.Split(string(name), "/") {
+		if s == "" || s == "." || s == ".." || strings.ContainsAny(s, `\:`) {
+			return ""
+		}
+	}
+	return string(name)
+}
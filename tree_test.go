@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_matchAny(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		rel      string
+		want     bool
+	}{
+		{[]string{"*.html"}, "index.html", true},
+		{[]string{"*.html"}, "sub/page.html", true},
+		{[]string{"*.html"}, "style.css", false},
+		{[]string{"*.css", "*.html"}, "style.css", true},
+		{[]string{"images/*.png"}, "images/logo.png", true},
+		{[]string{"images/*.png"}, "sub/images/logo.png", false},
+		{[]string{"drafts/"}, "drafts/a.html", true},
+		{[]string{"drafts/"}, "sub/drafts/deep/a.html", true},
+		{[]string{"drafts/"}, "drafts.html", false},
+		{[]string{"sub/drafts/"}, "sub/drafts/a.html", true},
+		{[]string{"sub/drafts/"}, "drafts/a.html", false},
+		{nil, "a.html", false},
+	}
+	for _, tt := range tests {
+		if got := matchAny(tt.patterns, tt.rel); got != tt.want {
+			t.Errorf("matchAny(%v, %q) = %v, want %v", tt.patterns, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func Test_expandTrees(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	for _, f := range []string{"help/index.html", "help/style.css", "help/notes.txt", "help/images/logo.png", "help/drafts/draft.html"} {
+		name := filepath.Join(tmpDir, filepath.FromSlash(f))
+		err := os.MkdirAll(filepath.Dir(name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(name, []byte(f), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res := jsonDef{
+		"RT_HTML": {
+			"*": {"0409": map[string]interface{}{
+				"dir":     "help",
+				"include": []interface{}{"*.html", "*.css", "images/"},
+				"exclude": []interface{}{"drafts/"},
+			}},
+			"STYLE.CSS": {"0409": "other.css"},
+		},
+		"RT_RCDATA": {
+			"DOC/*": {"040C": "help/images"},
+		},
+	}
+	err := expandTrees(tmpDir, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := jsonDef{
+		"RT_HTML": {
+			"INDEX.HTML":      {"0409": filepath.Join("help", "index.html")},
+			"STYLE.CSS":       {"0409": "other.css"},
+			"IMAGES/LOGO.PNG": {"0409": filepath.Join("help", "images", "logo.png")},
+		},
+		"RT_RCDATA": {
+			"DOC/LOGO.PNG": {"040C": filepath.Join("help", "images", "logo.png")},
+		},
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("expandTrees() =\n%v\nwant\n%v", res, want)
+	}
+
+	errTests := []struct {
+		res jsonDef
+		err string
+	}{
+		{jsonDef{"RT_BITMAP": {"*": {"0409": "help"}}}, "[RT_BITMAP][*] " + errTreeType},
+		{jsonDef{"RT_HTML": {"*": {"0409": 42.0}}}, "[RT_HTML][*][0409] " + errInvalidTree},
+		{jsonDef{"RT_HTML": {"*": {"0409": map[string]interface{}{"include": []interface{}{"*"}}}}}, "[RT_HTML][*][0409] " + errInvalidTree},
+		{jsonDef{"RT_HTML": {"*": {"0409": map[string]interface{}{"dir": "help", "include": []interface{}{"[a-"}}}}}, `[RT_HTML][*][0409] syntax error in pattern: "[a-"`},
+		{jsonDef{"RT_HTML": {"*": {"0409": "help"}, "IMAGES/*": {"0409": "help/images"}}}, `[RT_HTML][IMAGES/*][0409] "IMAGES/LOGO.PNG" is also imported by "*"`},
+		{jsonDef{"RT_BITMAP": {"*": {"0409": "help"}}, "RT_HTML": {"*": {"0409": 42.0, "040C": 42.0}}}, "[RT_BITMAP][*] " + errTreeType + "\n[RT_HTML][*][0409] " + errInvalidTree + "\n[RT_HTML][*][040C] " + errInvalidTree},
+	}
+	for _, tt := range errTests {
+		err := expandTrees(tmpDir, tt.res)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("expandTrees() error = %v, want %q", err, tt.err)
+		}
+	}
+}
+
+func Test_treePath(t *testing.T) {
+	tests := []struct {
+		typeID winres.Identifier
+		resID  winres.Identifier
+		all    bool
+		want   string
+	}{
+		{winres.RT_HTML, winres.Name("IMAGES/LOGO.PNG"), false, "IMAGES/LOGO.PNG"},
+		{winres.RT_RCDATA, winres.Name("A/B/C"), false, "A/B/C"},
+		{winres.RT_HTML, winres.Name("INDEX.HTML"), false, ""},
+		{winres.RT_HTML, winres.Name("INDEX.HTML"), true, "INDEX.HTML"},
+		{winres.RT_HTML, winres.ID(1), true, ""},
+		{winres.RT_BITMAP, winres.Name("A/B"), true, ""},
+		{winres.RT_HTML, winres.Name("../EVIL"), true, ""},
+		{winres.RT_HTML, winres.Name("/ROOT"), true, ""},
+		{winres.RT_HTML, winres.Name(`A\B/C`), true, ""},
+		{winres.RT_HTML, winres.Name("C:/X"), true, ""},
+	}
+	for _, tt := range tests {
+		if got := treePath(tt.typeID, tt.resID, tt.all); got != tt.want {
+			t.Errorf("treePath(%v, %v, %v) = %q, want %q", tt.typeID, tt.resID, tt.all, got, tt.want)
+		}
+	}
+}
+
+func Test_exportResources_Tree(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	rs := &winres.ResourceSet{}
+	rs.Set(winres.RT_HTML, winres.Name("INDEX.HTML"), 0x409, []byte("index"))
+	rs.Set(winres.RT_HTML, winres.Name("IMAGES/LOGO.PNG"), 0x409, []byte("logo"))
+	rs.Set(winres.RT_RCDATA, winres.Name("DATA"), 0x409, []byte("data"))
+
+	exportResources(tmpDir, rs, exportOptions{format: formatJSON, tree: true})
+
+	b, err := ioutil.ReadFile(filepath.Join(tmpDir, "RT_HTML_0409", "IMAGES", "LOGO.PNG"))
+	if err != nil || string(b) != "logo" {
+		t.Errorf("wrong tree file %q (%v)", b, err)
+	}
+
+	rs2 := &winres.ResourceSet{}
+	err = importResources(rs2, filepath.Join(tmpDir, "winres.json"), importOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Walk(func(typeID, resID winres.Identifier, langID uint16, data []byte) bool {
+		if string(rs2.Get(typeID, resID, langID)) != string(data) {
+			t.Errorf("[%v][%v][%04X] differs", typeID, resID, langID)
+		}
+		return true
+	})
+	if rs2.Count() != rs.Count() {
+		t.Errorf("wrong number of resources (%d, want %d)", rs2.Count(), rs.Count())
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tc-hib/winres"
+)
+
+const errUnknownType = "unknown resource type"
+
+var (
+	identityKeys = []string{"name", "version"}
+	versionKeys  = []string{"fixed", "info"}
+	fixedKeys    = []string{"file_version", "product_version", "flags", "type", "timestamp"}
+	versionFlags = []string{"Debug", "Prerelease", "Patched", "PrivateBuild", "SpecialBuild"}
+	versionTypes = []string{"App", "DLL", "Unknown"}
+	infoKeys     = []string{
+		"Comments", "CompanyName", "FileDescription", "FileVersion", "InternalName", "LegalCopyright",
+		"LegalTrademarks", "OriginalFilename", "PrivateBuild", "ProductName", "ProductVersion", "SpecialBuild",
+	}
+)
+
+// validateResources checks a resource definition without writing anything.
+//
+// Every resource is imported, and errors are collected rather than returned on the first one,
+// so that all problems are reported at once.
+// The definition is also checked by checkDef, as with the strict option.
+//
+// Warnings are about icons that would be made, but might not look right.
+func validateResources(name string, opt importOptions) (problems []error, warnings []error) {
+	if isRESFile(name) {
+		err := importRES(&winres.ResourceSet{}, name)
+		if err != nil {
+			return []error{err}, nil
+		}
+		return nil, nil
+	}
+
+	res, includes, err := loadDef(name, opt)
+	if res == nil {
+		return []error{err}, nil
+	}
+
+	var errs errorList
+	errs.add(err)
+	dir := filepath.Dir(name)
+	for _, inc := range includes {
+		if !isRESFile(inc) {
+			errs.add(fmt.Errorf("cannot include %q, only .res files can be included", inc))
+			continue
+		}
+		errs.add(importRES(&winres.ResourceSet{}, filepath.Join(dir, inc)))
+	}
+
+	errs.add(checkDef(res))
+
+	rs := &winres.ResourceSet{}
+	errs.add(importDef(rs, dir, res))
+
+	var lint errorList
+	lint.add(lintIcons(rs, dir, res))
+
+	var located, lintLocated errorList
+	located.add(locateErrors(name, errs.err()))
+	lintLocated.add(locateErrors(name, lint.err()))
+	return located, lintLocated
+}
+
+// checkDef reports what importDef would silently ignore:
+// misspelled resource types, and unknown keys in the manifest and the version info.
+//
+// Unknown types are removed from the definition, so they are not reported twice.
+func checkDef(res jsonDef) error {
+	var errs errorList
+	for _, tid := range sortedTypes(res) {
+		if _, ok := typeIDFromString[tid]; !ok && strings.HasPrefix(tid, "RT_") {
+			errs.add(atPath(fmt.Errorf("%s%s", errUnknownType, didYouMean(tid, typeNames())), tid))
+			delete(res, tid)
+			continue
+		}
+		for _, r := range sortedRes(res[tid]) {
+			for _, l := range sortedLang(r.langs) {
+				m, ok := l.data.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				switch tid {
+				case "RT_MANIFEST":
+					errs.add(atPath(checkManifest(m), tid, r.id, l.id))
+				case "RT_VERSION":
+					errs.add(atPath(checkVersionInfo(m), tid, r.id, l.id))
+				}
+			}
+		}
+	}
+	return errs.err()
+}
+
+func typeNames() []string {
+	names := make([]string, 0, len(typeIDFromString))
+	for s := range typeIDFromString {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// manifestKeys returns the json keys of an application manifest.
+func manifestKeys() []string {
+	b, _ := json.Marshal(winres.AppManifest{})
+	var m map[string]interface{}
+	json.Unmarshal(b, &m)
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func checkManifest(m map[string]interface{}) error {
+	var errs errorList
+	errs.add(checkKeys(m, manifestKeys()))
+	if id, ok := m["identity"].(map[string]interface{}); ok {
+		errs.add(checkKeys(id, identityKeys, "identity"))
+	}
+	return errs.err()
+}
+
+func checkVersionInfo(m map[string]interface{}) error {
+	var errs errorList
+	errs.add(checkKeys(m, versionKeys))
+
+	if fixed, ok := m["fixed"].(map[string]interface{}); ok {
+		errs.add(checkKeys(fixed, fixedKeys, "fixed"))
+		for _, k := range []string{"file_version", "product_version"} {
+			if s, ok := fixed[k].(string); ok && !isVersionNumber(s) {
+				errs.add(atPath(fmt.Errorf("invalid version number %q", s), "fixed", k))
+			}
+		}
+		if s, ok := fixed["flags"].(string); ok {
+			for _, f := range strings.Split(s, ",") {
+				if f = strings.TrimSpace(f); f != "" && !containsFold(versionFlags, f) {
+					errs.add(atPath(fmt.Errorf("unknown flag %q%s", f, didYouMean(f, versionFlags)), "fixed", "flags"))
+				}
+			}
+		}
+		if s, ok := fixed["type"].(string); ok && s != "" && !containsFold(versionTypes, s) {
+			errs.add(atPath(fmt.Errorf("unknown file type %q%s", s, didYouMean(s, versionTypes)), "fixed", "type"))
+		}
+	}
+
+	info, _ := m["info"].(map[string]interface{})
+	for _, l := range sortedLang(info) {
+		if _, err := strconv.ParseUint(l.id, 16, 16); err != nil {
+			errs.add(atPath(fmt.Errorf("invalid language identifier %q", l.id), "info", l.id))
+		}
+		st, ok := l.data.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, k := range sortedLang(st) {
+			if _, ok := k.data.(string); !ok {
+				errs.add(atPath(errors.New("value should be a string"), "info", l.id, k.id))
+			}
+			// Other keys are allowed, unless they look like a misspelled standard key
+			if s := didYouMean(k.id, infoKeys); s != "" && !contains(infoKeys, k.id) {
+				errs.add(atPath(fmt.Errorf("unknown key%s", s), "info", l.id, k.id))
+			}
+		}
+	}
+
+	return errs.err()
+}
+
+// checkKeys reports keys that are not in the known list.
+func checkKeys(m map[string]interface{}, known []string, path ...string) error {
+	var errs errorList
+	for _, l := range sortedLang(m) {
+		if !contains(known, l.id) {
+			errs.add(atPath(fmt.Errorf("unknown key%s", didYouMean(l.id, known)), append(path, l.id)...))
+		}
+	}
+	return errs.err()
+}
+
+// didYouMean returns a suggestion for a misspelled word, or an empty string.
+func didYouMean(s string, known []string) string {
+	best, dist := "", 3
+	for _, k := range known {
+		if strings.EqualFold(s, k) {
+			return fmt.Sprintf(", did you mean %q?", k)
+		}
+		if d := editDistance(strings.ToLower(s), strings.ToLower(k)); d < dist {
+			best, dist = k, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// editDistance is the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isVersionNumber tells if a string is made of up to four numbers, such as "1.2.3.4".
+func isVersionNumber(s string) bool {
+	if s == "" {
+		return true
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 4 {
+		return false
+	}
+	for _, p := range parts {
+		if _, err := strconv.ParseUint(p, 10, 16); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/tc-hib/winres"
+)
+
+func Test_validateResources(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	copyFile(t, filepath.Join("_testdata", "cur-64x128.png"), filepath.Join(tmpDir, "icon.png"))
+
+	name := filepath.Join(tmpDir, "winres.json")
+	err := ioutil.WriteFile(name, []byte(`{
+  "$include": "legacy.rc",
+  "RT_GROUP_ICON": {
+    "APP": {"0000": ["icon.png", "missing.png"]},
+    "OK": {"0000": "icon.png"}
+  },
+  "RT_MANIFST": {},
+  "RT_MANIFEST": {
+    "#1": {
+      "0409": {
+        "dpi-awarness": "per monitor v2",
+        "identity": {"name": "a", "verison": "1.0"},
+        "minimum-os": "win11"
+      }
+    }
+  },
+  "RT_VERSION": {
+    "#1": {
+      "0000": {
+        "fixed": {"file_version": "1.x", "flags": "Debug,Prerelese", "typ": "DLL"},
+        "info": {
+          "0409": {"CompanyNam": "", "companyname": "", "ProductName": "", "BuildDate": ""},
+          "x": {}
+        }
+      }
+    }
+  },
+  "RT_STRING": {
+    "0409": {"1": "a", "x": "b"}
+  },
+  "MY_TYPE": {
+    "A": {"0409": "a.bin", "zz": {"text": "a"}}
+  }
+}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`cannot include "legacy.rc", only .res files can be included`,
+		name + ":4:34: [RT_GROUP_ICON][APP][0000][1] open " + filepath.Join(tmpDir, "missing.png") + ": no such file or directory",
+		name + `:7:3: [RT_MANIFST] unknown resource type, did you mean "RT_MANIFEST"?`,
+		name + ":10:7: [RT_MANIFEST][#1][0409] unknown minimum-os value",
+		name + `:11:9: [RT_MANIFEST][#1][0409][dpi-awarness] unknown key, did you mean "dpi-awareness"?`,
+		name + `:12:35: [RT_MANIFEST][#1][0409][identity][verison] unknown key, did you mean "version"?`,
+		name + `:20:19: [RT_VERSION][#1][0000][fixed][file_version] invalid version number "1.x"`,
+		name + `:20:42: [RT_VERSION][#1][0000][fixed][flags] unknown flag "Prerelese", did you mean "Prerelease"?`,
+		name + `:20:70: [RT_VERSION][#1][0000][fixed][typ] unknown key, did you mean "type"?`,
+		name + `:22:20: [RT_VERSION][#1][0000][info][0409][CompanyNam] unknown key, did you mean "CompanyName"?`,
+		name + `:22:38: [RT_VERSION][#1][0000][info][0409][companyname] unknown key, did you mean "CompanyName"?`,
+		name + `:23:11: [RT_VERSION][#1][0000][info][x] invalid language identifier "x"`,
+		name + ":29:24: [RT_STRING][0409][x] " + errInvalidStringID + `: "x"`,
+		name + ":32:11: [MY_TYPE][A][0409] open " + filepath.Join(tmpDir, "a.bin") + ": no such file or directory",
+		name + `:32:28: [MY_TYPE][A][zz] invalid language identifier "zz"`,
+	}
+	problems, _ := validateResources(name, importOptions{})
+	for i := 0; i < len(problems) || i < len(want); i++ {
+		var got, w string
+		if i < len(problems) {
+			got = problems[i].Error()
+		}
+		if i < len(want) {
+			w = want[i]
+		}
+		if got != w {
+			t.Errorf("problem %d = %q, want %q", i, got, w)
+		}
+	}
+
+	problems, _ = validateResources(filepath.Join("_testdata", "test.json"), importOptions{})
+	if len(problems) != 0 {
+		t.Errorf("test.json should be valid: %v", problems)
+	}
+}
+
+func Test_didYouMean(t *testing.T) {
+	known := []string{"dpi-awareness", "disable-theming", "ui-access"}
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"dpi-awarness", `, did you mean "dpi-awareness"?`},
+		{"DPI-Awareness", `, did you mean "dpi-awareness"?`},
+		{"ui-acess", `, did you mean "ui-access"?`},
+		{"something-else", ""},
+	}
+	for _, tt := range tests {
+		if got := didYouMean(tt.s, known); got != tt.want {
+			t.Errorf("didYouMean(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func Test_importResources_StrictKeys(t *testing.T) {
+	defer makeTmpDir(t)()
+
+	name := filepath.Join(tmpDir, "winres.json")
+	err := ioutil.WriteFile(name, []byte(`{
+  "RT_MANIFEST": {
+    "#1": {
+      "0409": {"dpi-awarness": "per monitor v2"}
+    }
+  }
+}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = importResources(&winres.ResourceSet{}, name, importOptions{}); err != nil {
+		t.Errorf("unknown keys should be ignored without --strict: %v", err)
+	}
+
+	err = importResources(&winres.ResourceSet{}, name, importOptions{strict: true})
+	want := name + `:4:16: [RT_MANIFEST][#1][0409][dpi-awarness] unknown key, did you mean "dpi-awareness"?`
+	if err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}